@@ -25,16 +25,13 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	var protocols []minecraft.Protocol
+	for _, pr := range legacyver.All() {
+		protocols = append(protocols, pr)
+	}
 	listener, err := minecraft.ListenConfig{
-		StatusProvider: p,
-		AcceptedProtocols: []minecraft.Protocol{
-			legacyver.New748(),
-			legacyver.New729(),
-			legacyver.New712(),
-			legacyver.New686(),
-			legacyver.New685(),
-			legacyver.New671(),
-		},
+		StatusProvider:    p,
+		AcceptedProtocols: protocols,
 	}.Listen("raknet", config.Connection.LocalAddress)
 	if err != nil {
 		panic(err)
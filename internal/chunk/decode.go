@@ -41,28 +41,44 @@ func NetworkDecode(air uint32, buf *bytes.Buffer, count int, oldFormat bool, r c
 				}
 			}
 		}
-	} else {
-		var last *PalettedStorage
-		for i := 0; i < len(c.sub); i++ {
-			b, err := decodePalettedStorage(buf, NetworkEncoding, pse, BiomePaletteEncoding)
-			if err != nil {
-				return nil, err
-			}
-			if b == nil {
-				// b == nil means this paletted storage had the flag pointing to the previous one. It basically means we should
-				// inherit whatever palette we decoded last.
-				if i == 0 {
-					// This should never happen and there is no way to handle this.
-					return nil, fmt.Errorf("first biome storage pointed to previous one")
-				}
-				b = last
-			} else {
-				last = b
+	} else if err := decodeBiomes(buf, c, pse); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NetworkDecodeBiomes decodes the network serialised biomes passed into a Chunk that only holds biomes. One biome
+// storage is read for every sub chunk in the range passed.
+func NetworkDecodeBiomes(air uint32, buf *bytes.Buffer, r cube.Range, pse Encoding) (*Chunk, error) {
+	c := New(air, r)
+	if err := decodeBiomes(buf, c, pse); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// decodeBiomes decodes the network serialised 3D biomes of a chunk into the Chunk passed.
+func decodeBiomes(buf *bytes.Buffer, c *Chunk, pse Encoding) error {
+	var last *PalettedStorage
+	for i := 0; i < len(c.sub); i++ {
+		b, err := decodePalettedStorage(buf, NetworkEncoding, pse, BiomePaletteEncoding)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			// b == nil means this paletted storage had the flag pointing to the previous one. It basically means we should
+			// inherit whatever palette we decoded last.
+			if i == 0 {
+				// This should never happen and there is no way to handle this.
+				return fmt.Errorf("first biome storage pointed to previous one")
 			}
-			c.biomes[i] = b
+			b = last
+		} else {
+			last = b
 		}
+		c.biomes[i] = b
 	}
-	return c, nil
+	return nil
 }
 
 // DecodeSubChunk decodes a SubChunk from a bytes.Buffer. The Encoding passed defines how the block storages of the
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/df-mc/worldupgrader/blockupgrader"
+	"github.com/segmentio/fasthash/fnv1a"
+	"slices"
+)
+
+// unknownBlockHash is the network hash of the minecraft:unknown block, which the client doesn't compute the hash of.
+const unknownBlockHash = 0xfffffffe
+
+// NetworkBlockHash returns the network ID of a block state used when UseBlockNetworkIDHashes is enabled. It is the
+// 32-bit FNV-1a hash of the name and properties of the state, encoded as little endian NBT with the properties
+// sorted by name. An error is returned if the state has a property of a type that block states can't have.
+func NetworkBlockHash(state blockupgrader.BlockState) (uint32, error) {
+	if state.Name == "minecraft:unknown" {
+		return unknownBlockHash, nil
+	}
+	b := make([]byte, 0, 128)
+	b = append(b, 10, 0, 0)
+	b = appendNBTName(b, 8, "name")
+	b = appendNBTString(b, state.Name)
+	b = appendNBTName(b, 10, "states")
+
+	keys := make([]string, 0, len(state.Properties))
+	for k := range state.Properties {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		switch v := state.Properties[k].(type) {
+		case bool:
+			b = appendNBTName(b, 1, k)
+			if v {
+				b = append(b, 1)
+			} else {
+				b = append(b, 0)
+			}
+		case uint8:
+			b = appendNBTName(b, 1, k)
+			b = append(b, v)
+		case int32:
+			b = appendNBTName(b, 3, k)
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		case string:
+			b = appendNBTName(b, 8, k)
+			b = appendNBTString(b, v)
+		default:
+			return 0, fmt.Errorf("invalid block property type %T for property %v of %v", v, k, state.Name)
+		}
+	}
+	// End the states and the root compound.
+	b = append(b, 0, 0)
+	return fnv1a.HashBytes32(b), nil
+}
+
+// appendNBTName appends the type and name of a named NBT tag to b.
+func appendNBTName(b []byte, tag byte, name string) []byte {
+	return appendNBTString(append(b, tag), name)
+}
+
+// appendNBTString appends a little endian NBT string to b.
+func appendNBTString(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
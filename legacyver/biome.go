@@ -0,0 +1,35 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/mapping"
+)
+
+// biomeFallbacks holds the nearest equivalent of vanilla biomes that don't exist in every supported legacy version.
+// A biome that doesn't exist in a legacy version is replaced by its fallback, which is resolved again until a biome
+// known to the legacy version is found.
+var biomeFallbacks = map[string]string{
+	"pale_garden": "roofed_forest",
+}
+
+// defaultBiome is the biome used if neither a biome nor any of its fallbacks exist in a legacy version.
+const defaultBiome = "plains"
+
+// downgradeBiomeName returns the name of the biome that should be sent to a client using the legacy mapping passed
+// in place of the biome name passed.
+func downgradeBiomeName(legacy mapping.Biome, name string) string {
+	if fallback, ok := nearestBiomeName(legacy, name); ok {
+		return fallback
+	}
+	return defaultBiome
+}
+
+// nearestBiomeName returns the biome name passed, or the nearest of its fallbacks that exists in the legacy mapping
+// passed. False is returned if neither the biome nor any of its fallbacks exist in the legacy mapping.
+func nearestBiomeName(legacy mapping.Biome, name string) (string, bool) {
+	for fallback := name; fallback != ""; fallback = biomeFallbacks[fallback] {
+		if _, ok := legacy.BiomeNameToID(fallback); ok {
+			return fallback, true
+		}
+	}
+	return "", false
+}
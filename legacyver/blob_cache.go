@@ -0,0 +1,106 @@
+package legacyver
+
+import (
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/segmentio/fasthash/fnv1"
+)
+
+// blobCacheVersion is mixed into the hashes of all translated blobs. It must be changed whenever the way blobs are
+// translated changes, so that clients don't use blobs they cached while running an older version of the library.
+const blobCacheVersion = 1
+
+// blobFingerprinter is implemented by block translators that can describe the state their blob translation
+// depends on, such as DefaultBlockTranslator.
+type blobFingerprinter interface {
+	// blobFingerprint returns a hash that changes whenever the blobs translated by the translator would change.
+	blobFingerprint() uint64
+}
+
+// blobFingerprint returns a hash of everything the translation of a blob for the connection passed depends on:
+// the protocol, the version of the library, the block runtime IDs and substitutions of the block translator, the
+// use of hashed block network IDs and the height range of the dimension the blob belongs to. Clients keep their
+// blob cache across sessions, so the translated hash of a blob must change whenever one of these does.
+func (p *Protocol) blobFingerprint(conn *minecraft.Conn, r cube.Range) uint64 {
+	hash := fnv1.HashUint64(uint64(p.id))
+	hash = fnv1.AddUint64(hash, blobCacheVersion)
+	if f, ok := p.blockTranslator.(blobFingerprinter); ok {
+		hash = fnv1.AddUint64(hash, f.blobFingerprint())
+	}
+	if stateOf(conn).usesHashedBlockIDs() {
+		hash = fnv1.AddUint64(hash, 1)
+	}
+	hash = fnv1.AddUint64(hash, uint64(int64(r.Min())))
+	return fnv1.AddUint64(hash, uint64(int64(r.Max())))
+}
+
+// downgradeBlobHashes translates the blob hashes of a LevelChunk passed for the connection passed and returns them
+// in a new slice. The blobs belong to a dimension with the range passed. The translated hashes are remembered, so
+// that the hashes the client sends back can be translated to the hashes the server knows.
+func (p *Protocol) downgradeBlobHashes(hashes []uint64, conn *minecraft.Conn, r cube.Range) []uint64 {
+	if len(hashes) == 0 {
+		return hashes
+	}
+	fingerprint, state := p.blobFingerprint(conn, r), stateOf(conn)
+	translated := make([]uint64, len(hashes))
+	for i, hash := range hashes {
+		translated[i] = hash ^ fingerprint
+		state.addBlob(hash, translated[i])
+	}
+	// The last blob of a chunk holds its biomes, whether its sub chunks are sent along with it or not.
+	state.addBiomeBlob(hashes[len(hashes)-1])
+	return translated
+}
+
+// upgradeBlobHashes translates the blob hashes sent by the client back to the hashes the server sent. If forget
+// is true, the hashes are forgotten, as the client won't refer to them again.
+func (p *Protocol) upgradeBlobHashes(hashes []uint64, conn *minecraft.Conn, forget bool) []uint64 {
+	if len(hashes) == 0 {
+		return hashes
+	}
+	state := stateOf(conn)
+	upgraded := make([]uint64, len(hashes))
+	for i, hash := range hashes {
+		latest, ok := state.latestBlob(hash, forget)
+		if !ok {
+			// The hash was never sent to the client, so there is nothing to translate it to.
+			latest = hash
+		}
+		upgraded[i] = latest
+	}
+	return upgraded
+}
+
+// downgradeSubChunkHashes translates the blob hashes of the sub chunk entries passed and returns them in a new
+// slice.
+func (p *Protocol) downgradeSubChunkHashes(pk *packet.SubChunk, conn *minecraft.Conn) []protocol.SubChunkEntry {
+	fingerprint, state := p.blobFingerprint(conn, stateOf(conn).dimensionRange(pk.Dimension)), stateOf(conn)
+	entries := make([]protocol.SubChunkEntry, len(pk.SubChunkEntries))
+	for i, entry := range pk.SubChunkEntries {
+		if pk.CacheEnabled && entry.Result == protocol.SubChunkResultSuccess {
+			translated := entry.BlobHash ^ fingerprint
+			state.addBlob(entry.BlobHash, translated)
+			entry.BlobHash = translated
+		}
+		entries[i] = entry
+	}
+	return entries
+}
+
+// downgradeCacheBlobs translates the hashes of the blobs passed to the hashes they were sent to the client with,
+// and returns them in a new slice. The hashes are forgotten, as the client caches the blobs sent.
+func (p *Protocol) downgradeCacheBlobs(blobs []protocol.CacheBlob, conn *minecraft.Conn) []protocol.CacheBlob {
+	state := stateOf(conn)
+	fingerprint := p.blobFingerprint(conn, state.currentRange())
+	translated := make([]protocol.CacheBlob, len(blobs))
+	for i, blob := range blobs {
+		hash, ok := state.translatedBlob(blob.Hash)
+		if !ok {
+			hash = blob.Hash ^ fingerprint
+		}
+		translated[i] = protocol.CacheBlob{Hash: hash, Payload: blob.Payload}
+	}
+	return translated
+}
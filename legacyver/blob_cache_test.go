@@ -0,0 +1,66 @@
+package legacyver
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/akmalfairuz/legacy-version/internal/chunk"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestBiomeBlobRoundTrip(t *testing.T) {
+	p, conn := New748(), &minecraft.Conn{}
+	tr := p.blockTranslator.(*DefaultBlockTranslator)
+	paleGarden, _ := tr.latestBiomes.BiomeNameToID("pale_garden")
+	roofedForest, _ := tr.biomes.BiomeNameToID("roofed_forest")
+
+	r := world.Overworld.Range()
+	c := chunk.New(tr.latest.Air(), r)
+	c.SetBiome(0, 0, 0, paleGarden)
+	payload := chunk.EncodeBiomes(c, chunk.NetworkEncoding)
+
+	const hash = 0x1234
+	p.ConvertFromLatest(&packet.LevelChunk{
+		SubChunkCount: protocol.SubChunkRequestModeLimitless,
+		CacheEnabled:  true,
+		BlobHashes:    []uint64{hash},
+	}, conn)
+	translated, ok := stateOf(conn).blobs[hash]
+	if !ok {
+		t.Fatal("hash of the biome blob was not translated")
+	}
+
+	blobs := []protocol.CacheBlob{{Hash: hash, Payload: payload}}
+	pks := p.ConvertFromLatest(&packet.ClientCacheMissResponse{Blobs: blobs}, conn)
+	if len(pks) != 1 {
+		t.Fatalf("got %v packets, want 1", len(pks))
+	}
+	if !bytes.Equal(blobs[0].Payload, payload) {
+		t.Fatal("blobs of the latest packet were modified")
+	}
+	blob := pks[0].(*packet.ClientCacheMissResponse).Blobs[0]
+	if blob.Hash != translated {
+		t.Fatalf("blob hash = %x, want %x", blob.Hash, translated)
+	}
+	biomes, err := chunk.NetworkDecodeBiomes(tr.mapping.Air(), bytes.NewBuffer(blob.Payload), r, tr.pse)
+	if err != nil {
+		t.Fatalf("decode downgraded biomes: %v", err)
+	}
+	if id := biomes.Biome(0, 0, 0); id != roofedForest {
+		t.Fatalf("biome = %v, want %v", id, roofedForest)
+	}
+
+	// The chunk is sent again later, after which the client reports the blob it cached as a hit.
+	p.ConvertFromLatest(&packet.LevelChunk{
+		SubChunkCount: protocol.SubChunkRequestModeLimitless,
+		CacheEnabled:  true,
+		BlobHashes:    []uint64{hash},
+	}, conn)
+	pks = p.ConvertToLatest(&packet.ClientCacheBlobStatus{HitHashes: []uint64{translated}}, conn)
+	if hits := pks[0].(*packet.ClientCacheBlobStatus).HitHashes; len(hits) != 1 || hits[0] != hash {
+		t.Fatalf("hit hashes = %x, want [%x]", hits, uint64(hash))
+	}
+}
@@ -0,0 +1,184 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"slices"
+)
+
+// blockActorSchema describes a change made to the NBT of a block actor in a version of the game.
+type blockActorSchema struct {
+	// version is the protocol version in which the change was made.
+	version int32
+	// id is the ID of the block actor that changed, such as "Skull".
+	id string
+	// downgrade transforms the NBT of the version to that of the version before it, and upgrade does the
+	// opposite. Either may be nil if nothing needs to be done in that direction.
+	downgrade, upgrade func(actor map[string]any)
+}
+
+// blockActorSchemas holds all changes made to the NBT of block actors since the oldest supported version. Versions
+// that share block state data, such as 1.21.0 and 1.21.2, must have the same schemas applied, as they share a
+// block mapping.
+var blockActorSchemas = []blockActorSchema{
+	{
+		// Creaking hearts were added in 1.21.50. Their blocks are replaced for older clients, so their block actors
+		// are dropped.
+		version:   proto.ID766,
+		id:        "CreakingHeart",
+		downgrade: dropBlockActor,
+	},
+	{
+		// Skulls were split into a block per skull type in 1.21.40, after which the SkullType field is always 255.
+		// Older clients need the actual type, which the translator fills in where the block is known. Skeleton
+		// skulls are sent otherwise.
+		version: proto.ID748,
+		id:      "Skull",
+		downgrade: func(actor map[string]any) {
+			if skullType, ok := actor["SkullType"].(uint8); !ok || skullType == 255 {
+				actor["SkullType"] = uint8(0)
+			}
+		},
+		upgrade: func(actor map[string]any) {
+			actor["SkullType"] = uint8(255)
+		},
+	},
+	{
+		// Ominous vaults only open with ominous trial keys, which were added in 1.21.0 along with ominous bottles
+		// that they may show as loot. Older clients are shown a trial key instead, and no loot.
+		version: proto.ID685,
+		id:      "Vault",
+		downgrade: replaceItems(map[string]string{
+			"minecraft:ominous_trial_key": "minecraft:trial_key",
+			"minecraft:ominous_bottle":    "",
+		}),
+	},
+	{
+		// Ominous trial spawners drop ominous bottles, which were added in 1.21.0.
+		version:   proto.ID685,
+		id:        "TrialSpawner",
+		downgrade: replaceItems(map[string]string{"minecraft:ominous_bottle": ""}),
+	},
+	// Decorated pots can hold any item. Items that don't exist yet are removed from them. The sherds of the pots
+	// are left alone, as all sherds exist in every supported version.
+	{version: proto.ID685, id: "DecoratedPot", downgrade: removeItems(addedItems685)},
+	{version: proto.ID712, id: "DecoratedPot", downgrade: removeItems(addedItems712)},
+	{version: proto.ID748, id: "DecoratedPot", downgrade: removeItems(addedItems748)},
+	{version: proto.ID766, id: "DecoratedPot", downgrade: removeItems(addedItems766)},
+	// The NBT of signs and banners is the same in every supported version: signs have had front and back text since
+	// 1.20.0, and the flow and guster banner patterns of 1.21.0 already exist in 1.20.80.
+}
+
+var (
+	// addedItems685 holds the items added in 1.21.0 that don't replace an item that existed before.
+	addedItems685 = []string{
+		"minecraft:music_disc_creator", "minecraft:music_disc_creator_music_box", "minecraft:music_disc_precipice",
+		"minecraft:ominous_bottle", "minecraft:ominous_trial_key",
+	}
+	// addedItems712 holds the items added in 1.21.20 that don't replace an item that existed before.
+	addedItems712 = []string{"minecraft:bundle"}
+	// addedItems748 holds the items added in 1.21.40 that don't replace an item that existed before.
+	addedItems748 = []string{
+		"minecraft:black_bundle", "minecraft:blue_bundle", "minecraft:brown_bundle", "minecraft:cyan_bundle",
+		"minecraft:gray_bundle", "minecraft:green_bundle", "minecraft:light_blue_bundle", "minecraft:light_gray_bundle",
+		"minecraft:lime_bundle", "minecraft:magenta_bundle", "minecraft:orange_bundle", "minecraft:pink_bundle",
+		"minecraft:purple_bundle", "minecraft:red_bundle", "minecraft:white_bundle", "minecraft:yellow_bundle",
+	}
+	// addedItems766 holds the items added in 1.21.50 that don't replace an item that existed before.
+	addedItems766 = []string{
+		"minecraft:chiseled_resin_bricks", "minecraft:closed_eyeblossom", "minecraft:creaking_heart",
+		"minecraft:creaking_spawn_egg", "minecraft:open_eyeblossom", "minecraft:pale_hanging_moss",
+		"minecraft:pale_moss_block", "minecraft:pale_moss_carpet", "minecraft:pale_oak_boat", "minecraft:pale_oak_button",
+		"minecraft:pale_oak_chest_boat", "minecraft:pale_oak_door", "minecraft:pale_oak_double_slab",
+		"minecraft:pale_oak_fence", "minecraft:pale_oak_fence_gate", "minecraft:pale_oak_hanging_sign",
+		"minecraft:pale_oak_leaves", "minecraft:pale_oak_log", "minecraft:pale_oak_planks",
+		"minecraft:pale_oak_pressure_plate", "minecraft:pale_oak_sapling", "minecraft:pale_oak_sign",
+		"minecraft:pale_oak_slab", "minecraft:pale_oak_stairs", "minecraft:pale_oak_standing_sign",
+		"minecraft:pale_oak_trapdoor", "minecraft:pale_oak_wall_sign", "minecraft:pale_oak_wood",
+		"minecraft:resin_block", "minecraft:resin_brick", "minecraft:resin_brick_double_slab",
+		"minecraft:resin_brick_slab", "minecraft:resin_brick_stairs", "minecraft:resin_brick_wall",
+		"minecraft:resin_bricks", "minecraft:resin_clump", "minecraft:stripped_pale_oak_log",
+		"minecraft:stripped_pale_oak_wood",
+	}
+)
+
+// replaceItems returns a function that replaces the items held by the NBT of a block actor with the items they map
+// to in the replacements passed. Items that map to an empty name are replaced with air.
+func replaceItems(replacements map[string]string) func(actor map[string]any) {
+	return func(actor map[string]any) {
+		for k, v := range actor {
+			actor[k] = translateNBT(v, func(m map[string]any) {
+				name, _ := m["Name"].(string)
+				replacement, ok := replacements[name]
+				if !ok {
+					return
+				}
+				if replacement == "" {
+					clear(m)
+					m["Name"], m["Count"], m["Damage"] = "", uint8(0), int16(0)
+					return
+				}
+				m["Name"] = replacement
+			}, func(map[string]any) {})
+		}
+	}
+}
+
+// removeItems returns a function that replaces the items passed with air in the NBT of a block actor.
+func removeItems(names []string) func(actor map[string]any) {
+	replacements := make(map[string]string, len(names))
+	for _, name := range names {
+		replacements[name] = ""
+	}
+	return replaceItems(replacements)
+}
+
+// dropBlockActor clears the NBT of a block actor, so that the block actor is not sent to the client.
+func dropBlockActor(actor map[string]any) {
+	clear(actor)
+}
+
+// blockActorRemapper returns the functions that downgrade the NBT of block actors from the latest version to the
+// protocol version passed and upgrade them back. They may be passed to mapping.DefaultBlockMapping's
+// WithBlockActorRemapper.
+func blockActorRemapper(id int32) (downgrader, upgrader func(map[string]any) map[string]any) {
+	var schemas []blockActorSchema
+	for _, schema := range blockActorSchemas {
+		if schema.version > id {
+			schemas = append(schemas, schema)
+		}
+	}
+	// Downgrading goes from the newest change to the oldest, upgrading the other way around.
+	slices.SortStableFunc(schemas, func(a, b blockActorSchema) int {
+		return int(b.version - a.version)
+	})
+
+	downgrader = func(actor map[string]any) map[string]any {
+		for _, schema := range schemas {
+			if id, _ := actor["id"].(string); id == schema.id && schema.downgrade != nil {
+				schema.downgrade(actor)
+			}
+		}
+		return actor
+	}
+	upgrader = func(actor map[string]any) map[string]any {
+		for i := len(schemas) - 1; i >= 0; i-- {
+			if id, _ := actor["id"].(string); id == schemas[i].id && schemas[i].upgrade != nil {
+				schemas[i].upgrade(actor)
+			}
+		}
+		return actor
+	}
+	return downgrader, upgrader
+}
+
+// skullTypes holds the SkullType of every skull block, as used by the Skull block actor before skulls were split
+// into a block per type.
+var skullTypes = map[string]uint8{
+	"minecraft:skeleton_skull":        0,
+	"minecraft:wither_skeleton_skull": 1,
+	"minecraft:zombie_head":           2,
+	"minecraft:player_head":           3,
+	"minecraft:creeper_head":          4,
+	"minecraft:dragon_head":           5,
+	"minecraft:piglin_head":           6,
+}
@@ -0,0 +1,125 @@
+package legacyver
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/akmalfairuz/legacy-version/mapping"
+	"github.com/df-mc/worldupgrader/blockupgrader"
+)
+
+// BlockSubstitution describes the block that replaces a block of the latest version that doesn't exist in a
+// legacy version.
+type BlockSubstitution struct {
+	// Name is the name of the replacement block, as named in the latest version.
+	Name string `json:"name"`
+	// Properties holds the properties of the replacement block. Properties that aren't set are taken from the
+	// original block where possible.
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+// BlockFallbackKind is the way a block state without a legacy equivalent was replaced.
+type BlockFallbackKind uint8
+
+const (
+	// BlockFallbackProperties means the state was replaced by a state of the same block with other properties.
+	BlockFallbackProperties BlockFallbackKind = iota
+	// BlockFallbackSubstitution means the state was replaced using the block substitution table.
+	BlockFallbackSubstitution
+	// BlockFallbackAir means no replacement was found, so the state was replaced by air.
+	BlockFallbackAir
+)
+
+// BlockFallback describes a block state of the latest version that doesn't exist in a legacy version, and the
+// legacy block state it is replaced with.
+type BlockFallback struct {
+	// State is the block state of the latest version.
+	State blockupgrader.BlockState
+	// Replacement is the block state of the legacy version that is sent instead.
+	Replacement blockupgrader.BlockState
+	// Kind is the way the replacement was found.
+	Kind BlockFallbackKind
+}
+
+// parseBlockSubstitutions parses a block substitution table, indexed by the name of the block that is replaced.
+func parseBlockSubstitutions(raw []byte) map[string]BlockSubstitution {
+	var substitutions map[string]BlockSubstitution
+	if err := json.Unmarshal(raw, &substitutions); err != nil {
+		panic(err)
+	}
+	return substitutions
+}
+
+// legacyBlockState is a block state of a legacy version, together with its runtime ID.
+type legacyBlockState struct {
+	rid uint32
+	// properties are the properties of the equivalent state in the latest version.
+	properties map[string]any
+}
+
+// blockFallbackResolver finds the closest legacy block state for block states of the latest version that don't
+// exist in a legacy version.
+type blockFallbackResolver struct {
+	legacy        mapping.Block
+	substitutions map[string]BlockSubstitution
+	// states holds the states of the legacy mapping, indexed by the name of their equivalent in the latest version.
+	states map[string][]legacyBlockState
+}
+
+// newBlockFallbackResolver creates a blockFallbackResolver for the legacy mapping and substitution table passed.
+// The legacy states are indexed through the latest mapping, so that they can be looked up by their latest names.
+func newBlockFallbackResolver(legacy, latest mapping.Block, substitutions map[string]BlockSubstitution) *blockFallbackResolver {
+	states := make(map[string][]legacyBlockState)
+	for rid := uint32(0); ; rid++ {
+		state, ok := latest.RuntimeIDToState(rid)
+		if !ok {
+			break
+		}
+		if legacyRID, ok := legacy.StateToRuntimeID(state); ok {
+			states[state.Name] = append(states[state.Name], legacyBlockState{rid: legacyRID, properties: state.Properties})
+		}
+	}
+	return &blockFallbackResolver{legacy: legacy, substitutions: substitutions, states: states}
+}
+
+// resolve finds the legacy runtime ID that replaces the latest block state passed. The same block with the most
+// matching properties is preferred, then the block from the substitution table, and air if neither exist.
+func (r *blockFallbackResolver) resolve(state blockupgrader.BlockState) (uint32, BlockFallbackKind) {
+	if rid, ok := r.closest(state.Name, state.Properties); ok {
+		return rid, BlockFallbackProperties
+	}
+	if sub, ok := r.substitutions[state.Name]; ok {
+		properties := make(map[string]any, len(state.Properties)+len(sub.Properties))
+		for k, v := range state.Properties {
+			properties[k] = v
+		}
+		for k, v := range sub.Properties {
+			properties[k] = v
+		}
+		if rid, ok := r.closest(sub.Name, properties); ok {
+			return rid, BlockFallbackSubstitution
+		}
+	}
+	return r.legacy.Air(), BlockFallbackAir
+}
+
+// closest returns the runtime ID of the legacy state with the name passed that has the most properties in common
+// with the properties passed. If multiple states match equally well, the first one is returned.
+func (r *blockFallbackResolver) closest(name string, properties map[string]any) (uint32, bool) {
+	candidates, ok := r.states[name]
+	if !ok {
+		return 0, false
+	}
+	best, bestScore := candidates[0].rid, -1
+	for _, candidate := range candidates {
+		score := 0
+		for k, v := range candidate.properties {
+			if want, ok := properties[k]; ok && fmt.Sprint(want) == fmt.Sprint(v) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = candidate.rid, score
+		}
+	}
+	return best, true
+}
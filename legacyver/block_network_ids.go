@@ -0,0 +1,105 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/internal"
+	"github.com/akmalfairuz/legacy-version/mapping"
+	"github.com/df-mc/worldupgrader/blockupgrader"
+)
+
+// blockHashTable holds the network hashes of the block states of a legacy and the latest block mapping, which are
+// sent instead of runtime IDs if the server enables UseBlockNetworkIDHashes.
+type blockHashTable struct {
+	// legacyHashes and latestHashes are indexed by runtime ID.
+	legacyHashes, latestHashes []uint32
+	// legacyRIDs and latestRIDs map the hashes back to runtime IDs.
+	legacyRIDs, latestRIDs map[uint32]uint32
+}
+
+// hashTable returns the network hashes of the states of the mappings the table was built from, computing them
+// the first time it is called.
+func (table *runtimeIDTable) hashTable() *blockHashTable {
+	table.hashesOnce.Do(func() {
+		h := &blockHashTable{}
+		h.legacyHashes, h.legacyRIDs = hashBlockStates(table.legacy)
+		h.latestHashes, h.latestRIDs = hashBlockStates(table.latest)
+		table.hashes = h
+	})
+	return table.hashes
+}
+
+// hashBlockStates computes the network hash of every state of the block mapping passed. It returns the hashes
+// indexed by runtime ID and the runtime IDs indexed by hash. States that can't be hashed keep their runtime ID as
+// their network ID.
+func hashBlockStates(m mapping.Block) ([]uint32, map[uint32]uint32) {
+	var hashes []uint32
+	rids := make(map[uint32]uint32)
+	for rid := uint32(0); ; rid++ {
+		state, ok := m.RuntimeIDToState(rid)
+		if !ok {
+			return hashes, rids
+		}
+		h, err := internal.NetworkBlockHash(state)
+		if err != nil {
+			h = rid
+		}
+		hashes = append(hashes, h)
+		rids[h] = rid
+	}
+}
+
+// airHash is the network hash of air, which is the same in all versions.
+var airHash, _ = internal.NetworkBlockHash(blockupgrader.BlockState{Name: "minecraft:air"})
+
+// blockNetworkIDs translates the block network IDs sent over a connection. These are runtime IDs, or the network
+// hashes of the block states if the server enabled UseBlockNetworkIDHashes.
+type blockNetworkIDs struct {
+	// table is nil if the legacy and latest mappings are the same, in which case network IDs aren't translated.
+	table  *runtimeIDTable
+	hashed bool
+	// latestAir and legacyAir are the network IDs of air in the latest and legacy version.
+	latestAir, legacyAir uint32
+}
+
+// downgrade translates a network ID of the latest version to that of the legacy version.
+func (ids blockNetworkIDs) downgrade(id uint32) uint32 {
+	if ids.table == nil {
+		return id
+	}
+	if !ids.hashed {
+		return ids.table.downgradeRuntimeID(id)
+	}
+	h := ids.table.hashTable()
+	rid, ok := h.latestRIDs[id]
+	if !ok {
+		return ids.legacyAir
+	}
+	return h.legacyHashes[ids.table.downgradeRuntimeID(rid)]
+}
+
+// upgrade translates a network ID of the legacy version to that of the latest version.
+func (ids blockNetworkIDs) upgrade(id uint32) uint32 {
+	if ids.table == nil {
+		return id
+	}
+	if !ids.hashed {
+		return ids.table.upgradeRuntimeID(id)
+	}
+	h := ids.table.hashTable()
+	rid, ok := h.legacyRIDs[id]
+	if !ok {
+		return ids.latestAir
+	}
+	return h.latestHashes[ids.table.upgradeRuntimeID(rid)]
+}
+
+// latestRuntimeID returns the runtime ID in the latest mapping of a network ID of the latest version.
+func (ids blockNetworkIDs) latestRuntimeID(id uint32) (uint32, bool) {
+	if !ids.hashed {
+		return id, true
+	}
+	if ids.table == nil {
+		return 0, false
+	}
+	rid, ok := ids.table.hashTable().latestRIDs[id]
+	return rid, ok
+}
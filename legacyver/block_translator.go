@@ -6,11 +6,13 @@ import (
 	"github.com/akmalfairuz/legacy-version/internal/chunk"
 	"github.com/akmalfairuz/legacy-version/mapping"
 	"github.com/df-mc/dragonfly/server/block/cube"
-	"github.com/df-mc/dragonfly/server/world"
 	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/nbt"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"slices"
+	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -18,15 +20,12 @@ const (
 )
 
 var (
-	//go:embed data/block_states_766.nbt
-	latestBlockStateData []byte
-
-	// latestBlockMapping is the BlockMapping used for translating blocks between versions.
-	latestBlockMapping = mapping.NewBlockMapping(latestBlockStateData)
+	// lazyLatestMapping is the latest block mapping used by the package level encodings below.
+	lazyLatestMapping = &lazyLatestBlockMapping{}
 	// LatestNetworkPersistentEncoding is the Encoding used for sending a Chunk over network. It uses NBT, unlike NetworkEncoding.
-	LatestNetworkPersistentEncoding = chunk.NewNetworkPersistentEncoding(latestBlockMapping, BlockVersionLatest)
+	LatestNetworkPersistentEncoding = chunk.NewNetworkPersistentEncoding(lazyLatestMapping, BlockVersionLatest)
 	// LatestBlockPaletteEncoding is the paletteEncoding used for encoding a palette of block states encoded as NBT.
-	LatestBlockPaletteEncoding = chunk.NewBlockPaletteEncoding(latestBlockMapping, BlockVersionLatest)
+	LatestBlockPaletteEncoding = chunk.NewBlockPaletteEncoding(lazyLatestMapping, BlockVersionLatest)
 )
 
 type BlockTranslator interface {
@@ -37,37 +36,120 @@ type BlockTranslator interface {
 }
 
 type DefaultBlockTranslator struct {
-	mapping   mapping.Block
-	latest    mapping.Block
-	pse       chunk.Encoding
-	pe        chunk.PaletteEncoding
+	mapping mapping.Block
+	latest  mapping.Block
+	pse     chunk.Encoding
+	pe      chunk.PaletteEncoding
+	// lpse and lpe are the encodings of the latest version, built on the latest mapping of the translator.
+	lpse      chunk.Encoding
+	lpe       chunk.PaletteEncoding
 	oldFormat bool
+
+	// biomes and latestBiomes are the biome mappings used to translate biome IDs. If nil, every biome is replaced
+	// by ocean, as the client can't handle biome IDs it doesn't know.
+	biomes, latestBiomes mapping.Biome
+
+	// tables holds the runtime ID tables between the mappings. It is built on first use and rebuilt once one of
+	// the mappings is adjusted.
+	tables atomic.Pointer[runtimeIDTable]
+
+	substitutionsMu sync.Mutex
+	// substitutions holds the blocks that replace latest blocks which don't exist in the legacy version.
+	substitutions map[string]BlockSubstitution
 }
 
 func NewBlockTranslator(mapping mapping.Block, latestMapping mapping.Block, pse chunk.Encoding, pe chunk.PaletteEncoding, oldFormat bool) *DefaultBlockTranslator {
-	return &DefaultBlockTranslator{mapping: mapping, latest: latestMapping, pse: pse, pe: pe, oldFormat: oldFormat}
+	return &DefaultBlockTranslator{
+		mapping:   mapping,
+		latest:    latestMapping,
+		pse:       pse,
+		pe:        pe,
+		lpse:      chunk.NewNetworkPersistentEncoding(latestMapping, BlockVersionLatest),
+		lpe:       chunk.NewBlockPaletteEncoding(latestMapping, BlockVersionLatest),
+		oldFormat: oldFormat,
+	}
+}
+
+// WithBiomeMapping sets the biome mappings used to translate the biome IDs of chunks and returns the translator.
+func (t *DefaultBlockTranslator) WithBiomeMapping(biomes, latestBiomes mapping.Biome) *DefaultBlockTranslator {
+	t.biomes = biomes
+	t.latestBiomes = latestBiomes
+	return t
+}
+
+// WithBlockSubstitutions sets the block substitution table used for latest blocks that don't exist in the legacy
+// version and returns the translator.
+func (t *DefaultBlockTranslator) WithBlockSubstitutions(substitutions map[string]BlockSubstitution) *DefaultBlockTranslator {
+	t.substitutionsMu.Lock()
+	defer t.substitutionsMu.Unlock()
+	t.substitutions = substitutions
+	t.tables.Store(nil)
+	return t
+}
+
+// SetBlockSubstitution overrides the block that replaces the latest block with the name passed if it doesn't
+// exist in the legacy version.
+func (t *DefaultBlockTranslator) SetBlockSubstitution(name string, substitution BlockSubstitution) {
+	t.substitutionsMu.Lock()
+	defer t.substitutionsMu.Unlock()
+	substitutions := make(map[string]BlockSubstitution, len(t.substitutions)+1)
+	for k, v := range t.substitutions {
+		substitutions[k] = v
+	}
+	substitutions[name] = substitution
+	t.substitutions = substitutions
+	t.tables.Store(nil)
+}
+
+// BlockFallbacks returns the block states of the latest version that don't exist in the legacy version, together
+// with the legacy states they are replaced with.
+func (t *DefaultBlockTranslator) BlockFallbacks() []BlockFallback {
+	if t.latest == t.mapping {
+		return nil
+	}
+	return slices.Clone(t.runtimeIDTable().fallbacks)
 }
 
 func (t *DefaultBlockTranslator) DowngradeBlockPackets(pks []packet.Packet, conn *minecraft.Conn) (result []packet.Packet) {
 	for _, pk := range pks {
+		if start, ok := pk.(*packet.StartGame); ok {
+			stateOf(conn).setHashedBlockIDs(start.UseBlockNetworkIDHashes)
+		}
+		ids := t.networkIDs(conn)
 		switch pk := pk.(type) {
 		case *packet.LevelChunk:
 			count := int(pk.SubChunkCount)
 			if count == protocol.SubChunkRequestModeLimitless || count == protocol.SubChunkRequestModeLimited {
+				if !pk.CacheEnabled && t.biomes != nil {
+					// The sub chunks are requested separately, but the biomes are still sent in the payload.
+					pk.RawPayload = t.downgradeBiomePayload(pk.RawPayload, stateOf(conn).dimensionRange(pk.Dimension))
+				}
 				break
 			}
 
 			buf := bytes.NewBuffer(pk.RawPayload)
 			writeBuf := bytes.NewBuffer(nil)
+			var c *chunk.Chunk
 			if !pk.CacheEnabled {
-				c, err := chunk.NetworkDecode(t.latest.Air(), buf, count, false, world.Overworld.Range(), LatestNetworkPersistentEncoding, LatestBlockPaletteEncoding)
+				var err error
+				c, err = chunk.NetworkDecode(ids.latestAir, buf, count, false, stateOf(conn).dimensionRange(pk.Dimension), t.lpse, t.lpe)
 				if err != nil {
 					//fmt.Println(err)
 					break
 				}
-				c = t.DowngradeChunk(c)
+			}
+			// The block actors follow the blocks in the payload, but are downgraded first, as skulls need the
+			// blocks of the latest version.
+			tail := t.downgradeChunkTail(buf, func(x, y, z int32) (uint32, bool) {
+				if c == nil || y < int32(c.Range().Min()) || y > int32(c.Range().Max()) {
+					return 0, false
+				}
+				return ids.latestRuntimeID(c.Block(uint8(x&15), int16(y), uint8(z&15), 0))
+			})
+			if c != nil {
+				c = t.downgradeChunk(c, ids)
 
-				payload, err := chunk.NetworkEncode(t.mapping.Air(), c, t.oldFormat, t.pe)
+				payload, err := chunk.NetworkEncode(ids.legacyAir, c, t.oldFormat, t.pe)
 				if err != nil {
 					//fmt.Println(err)
 					break
@@ -75,109 +157,96 @@ func (t *DefaultBlockTranslator) DowngradeBlockPackets(pks []packet.Packet, conn
 				writeBuf.Write(payload)
 				pk.SubChunkCount = uint32(len(c.Sub()))
 			}
-			safeBytes := buf.Bytes()
-
-			countBorder, err := buf.ReadByte()
-			if err != nil {
-				pk.RawPayload = append(writeBuf.Bytes(), safeBytes...)
-				break
-			}
-			borderBytes := make([]byte, countBorder)
-			if _, err = buf.Read(borderBytes); err != nil {
-				pk.RawPayload = append(writeBuf.Bytes(), safeBytes...)
-				break
-			}
-			writeBuf.WriteByte(countBorder)
-			writeBuf.Write(borderBytes)
-
-			enc := nbt.NewEncoderWithEncoding(writeBuf, nbt.NetworkLittleEndian)
-			dec := nbt.NewDecoderWithEncoding(buf, nbt.NetworkLittleEndian)
-			for {
-				var decNbt map[string]any
-				if err = dec.Decode(&decNbt); err != nil {
-					break
-				}
-				t.mapping.DowngradeBlockActorData(decNbt)
-
-				if err = enc.Encode(decNbt); err != nil {
-					break
-				}
-			}
-			pk.RawPayload = append(writeBuf.Bytes(), buf.Bytes()...)
+			pk.RawPayload = append(writeBuf.Bytes(), tail...)
 		case *packet.SubChunk:
-			r := world.Overworld.Range()
+			r := stateOf(conn).dimensionRange(pk.Dimension)
 			if t.oldFormat {
 				r = cube.Range{0, 255}
 			}
-
-			for i, entry := range pk.SubChunkEntries {
+			entries := make([]protocol.SubChunkEntry, 0, len(pk.SubChunkEntries))
+			for _, entry := range pk.SubChunkEntries {
 				if entry.Result == protocol.SubChunkResultSuccess {
 					buf := bytes.NewBuffer(entry.RawPayload)
 					writeBuf := bytes.NewBuffer(nil)
-					if !pk.CacheEnabled && !conn.ClientCacheEnabled() {
-						ind := byte(i)
-						subChunk, err := chunk.DecodeSubChunk(t.latest.Air(), r, buf, &ind, chunk.NetworkEncoding, LatestNetworkPersistentEncoding, LatestBlockPaletteEncoding)
+					var subChunk *chunk.SubChunk
+					var ind byte
+					if !pk.CacheEnabled {
+						var err error
+						subChunk, err = chunk.DecodeSubChunk(ids.latestAir, r, buf, &ind, chunk.NetworkEncoding, t.lpse, t.lpe)
 						if err != nil {
 							//fmt.Println(err)
+							entries = append(entries, entry)
 							continue
 						}
-						t.DowngradeSubChunk(subChunk)
-						writeBuf.Write(chunk.EncodeSubChunk(subChunk, chunk.NetworkEncoding, t.pe, chunk.SubChunkVersion9, r, int(ind)))
 					}
-
-					enc := nbt.NewEncoderWithEncoding(writeBuf, nbt.NetworkLittleEndian)
-					dec := nbt.NewDecoderWithEncoding(buf, nbt.NetworkLittleEndian)
-					for {
-						var decNbt map[string]any
-						if err := dec.Decode(&decNbt); err != nil {
-							break
-						}
-						t.mapping.DowngradeBlockActorData(decNbt)
-
-						if err := enc.Encode(decNbt); err != nil {
-							break
+					actors := bytes.NewBuffer(nil)
+					t.downgradeBlockActors(buf, actors, func(x, y, z int32) (uint32, bool) {
+						if subChunk == nil {
+							return 0, false
 						}
+						return ids.latestRuntimeID(subChunk.Block(byte(x&15), byte(y&15), byte(z&15), 0))
+					})
+					if subChunk != nil {
+						t.downgradeSubChunk(subChunk, ids)
+						writeBuf.Write(t.encodeSubChunk(subChunk, r, int(ind)))
 					}
+					writeBuf.Write(actors.Bytes())
 
 					entry.RawPayload = append(writeBuf.Bytes(), buf.Bytes()...)
-					pk.SubChunkEntries[i] = entry
 				}
+				entries = append(entries, entry)
 			}
+			pk.SubChunkEntries = entries
 		case *packet.ClientCacheMissResponse:
-			r := world.Overworld.Range()
+			// Blobs don't carry their dimension, so they are assumed to be of the dimension the player is in.
+			state := stateOf(conn)
+			r := state.currentRange()
 			if t.oldFormat {
 				r = cube.Range{0, 255}
 			}
-
+			// The packet may be shared with other connections, so the blobs are downgraded into a new slice.
+			blobs := make([]protocol.CacheBlob, len(pk.Blobs))
 			for i, blob := range pk.Blobs {
+				blobs[i] = blob
 				buf := bytes.NewBuffer(blob.Payload)
+				if state.isBiomeBlob(blob.Hash) {
+					if t.biomes == nil {
+						continue
+					}
+					biomes, err := chunk.NetworkDecodeBiomes(ids.latestAir, buf, r, t.lpse)
+					if err != nil {
+						continue
+					}
+					t.downgradeBiomes(biomes)
+					blobs[i].Payload = chunk.EncodeBiomes(biomes, chunk.NetworkEncoding)
+					continue
+				}
 				ind := byte(0)
-				subChunk, err := chunk.DecodeSubChunk(t.latest.Air(), r, buf, &ind, chunk.NetworkEncoding, LatestNetworkPersistentEncoding, LatestBlockPaletteEncoding)
+				subChunk, err := chunk.DecodeSubChunk(ids.latestAir, r, buf, &ind, chunk.NetworkEncoding, t.lpse, t.lpe)
 				if err != nil {
-					// Has a possibility to be a biome, ignore then
 					continue
 				}
-				t.DowngradeSubChunk(subChunk)
+				t.downgradeSubChunk(subChunk, ids)
 
-				blob.Payload = append(chunk.EncodeSubChunk(subChunk, chunk.NetworkEncoding, t.pe, chunk.SubChunkVersion9, r, int(ind)), buf.Bytes()...)
-				pk.Blobs[i] = blob
-			}
-		case *packet.UpdateSubChunkBlocks:
-			for i, block := range pk.Blocks {
-				block.BlockRuntimeID = t.DowngradeBlockRuntimeID(block.BlockRuntimeID)
-				pk.Blocks[i] = block
+				blobs[i].Payload = append(t.encodeSubChunk(subChunk, r, int(ind)), buf.Bytes()...)
 			}
-			for i, block := range pk.Extra {
-				block.BlockRuntimeID = t.DowngradeBlockRuntimeID(block.BlockRuntimeID)
-				pk.Extra[i] = block
+			pk.Blobs = blobs
+		case *packet.BlockActorData:
+			t.mapping.DowngradeBlockActorData(pk.NBTData)
+			if len(pk.NBTData) == 0 {
+				// The block actor doesn't exist in the legacy version.
+				continue
 			}
+		case *packet.UpdateSubChunkBlocks:
+			pk.Blocks = t.downgradeBlockChangeEntries(pk.Blocks, ids)
+			pk.Extra = t.downgradeBlockChangeEntries(pk.Extra, ids)
 		case *packet.UpdateBlock:
-			pk.NewBlockRuntimeID = t.DowngradeBlockRuntimeID(pk.NewBlockRuntimeID)
+			pk.NewBlockRuntimeID = ids.downgrade(pk.NewBlockRuntimeID)
 		case *packet.UpdateBlockSynced:
-			pk.NewBlockRuntimeID = t.DowngradeBlockRuntimeID(pk.NewBlockRuntimeID)
+			pk.NewBlockRuntimeID = ids.downgrade(pk.NewBlockRuntimeID)
 		case *packet.InventoryTransaction:
 			if transactionData, ok := pk.TransactionData.(*protocol.UseItemTransactionData); ok {
-				transactionData.BlockRuntimeID = t.DowngradeBlockRuntimeID(transactionData.BlockRuntimeID)
+				transactionData.BlockRuntimeID = ids.downgrade(transactionData.BlockRuntimeID)
 				pk.TransactionData = transactionData
 			}
 		case *packet.LevelEvent:
@@ -187,10 +256,10 @@ func (t *DefaultBlockTranslator) DowngradeBlockPackets(pks []packet.Packet, conn
 			case packet.LevelEventParticlesDestroyBlock:
 				fallthrough
 			case packet.LevelEventParticlesDestroyBlockNoSound:
-				pk.EventData = int32(t.DowngradeBlockRuntimeID(uint32(pk.EventData)))
+				pk.EventData = int32(ids.downgrade(uint32(pk.EventData)))
 			case packet.LevelEventParticlesCrackBlock:
 				face := pk.EventData >> 24
-				rid := t.DowngradeBlockRuntimeID(uint32(pk.EventData & 0xffff))
+				rid := ids.downgrade(uint32(pk.EventData & 0xffff))
 				pk.EventData = int32(rid) | (face << 24)
 			}
 		case *packet.LevelSoundEvent:
@@ -204,17 +273,44 @@ func (t *DefaultBlockTranslator) DowngradeBlockPackets(pks []packet.Packet, conn
 			case packet.SoundEventLand:
 				fallthrough
 			case packet.SoundEventItemUseOn:
-				pk.ExtraData = int32(t.DowngradeBlockRuntimeID(uint32(pk.ExtraData)))
+				pk.ExtraData = int32(ids.downgrade(uint32(pk.ExtraData)))
 			}
 		case *packet.AddActor:
-			if pk.EntityType == "minecraft:falling_block" {
-				pk.EntityMetadata = t.downgradeEntityMetadata(pk.EntityMetadata)
+			blockVariant := pk.EntityType == "minecraft:falling_block"
+			if blockVariant {
+				stateOf(conn).addBlockEntity(pk.EntityUniqueID, pk.EntityRuntimeID)
 			}
+			pk.EntityMetadata = t.downgradeEntityMetadata(pk.EntityMetadata, ids, blockVariant)
+		case *packet.AddPlayer:
+			pk.EntityMetadata = t.downgradeEntityMetadata(pk.EntityMetadata, ids, false)
 		case *packet.SetActorData:
-			pk.EntityMetadata = t.downgradeEntityMetadata(pk.EntityMetadata)
+			pk.EntityMetadata = t.downgradeEntityMetadata(pk.EntityMetadata, ids, stateOf(conn).isBlockEntity(pk.EntityRuntimeID))
+		case *packet.RemoveActor:
+			stateOf(conn).removeEntity(pk.EntityUniqueID)
 		case *packet.StartGame:
+			stateOf(conn).setDimension(pk.Dimension)
 			t.latest.Adjust(pk.Blocks)
 			t.mapping.Adjust(pk.Blocks)
+			// Tables of mappings that keep track of their adjustments are only rebuilt if Adjust changed them, but
+			// the others can't tell and have to be rebuilt on every StartGame.
+			if !tracksAdjustments(t.latest) || !tracksAdjustments(t.mapping) {
+				t.tables.Store(nil)
+			}
+			if pk.SpawnBiomeType == packet.SpawnBiomeTypeUserDefined && t.biomes != nil {
+				// The spawn biome may be set to a vanilla biome that is newer than the client. It is replaced by its
+				// nearest equivalent, or by the default spawn biome if the client knows no equivalent.
+				if _, ok := t.latestBiomes.BiomeNameToID(pk.UserDefinedBiomeName); ok {
+					if name, ok := nearestBiomeName(t.biomes, pk.UserDefinedBiomeName); ok {
+						pk.UserDefinedBiomeName = name
+					} else {
+						pk.SpawnBiomeType, pk.UserDefinedBiomeName = packet.SpawnBiomeTypeDefault, ""
+					}
+				}
+			}
+		case *packet.ChangeDimension:
+			stateOf(conn).setDimension(pk.Dimension)
+		case *packet.DimensionData:
+			stateOf(conn).setDimensionDefinitions(pk.Definitions)
 		case *packet.ResourcePackStack:
 			var packs []protocol.StackResourcePack
 			for _, pack := range pk.TexturePacks {
@@ -231,15 +327,18 @@ func (t *DefaultBlockTranslator) DowngradeBlockPackets(pks []packet.Packet, conn
 }
 
 func (t *DefaultBlockTranslator) UpgradeBlockPackets(pks []packet.Packet, conn *minecraft.Conn) (result []packet.Packet) {
+	ids := t.networkIDs(conn)
 	for _, pk := range pks {
 		switch pk := pk.(type) {
 		case *packet.InventoryTransaction:
 			if transactionData, ok := pk.TransactionData.(*protocol.UseItemTransactionData); ok {
-				transactionData.BlockRuntimeID = t.UpgradeBlockRuntimeID(transactionData.BlockRuntimeID)
+				transactionData.BlockRuntimeID = ids.upgrade(transactionData.BlockRuntimeID)
 				pk.TransactionData = transactionData
 			}
 		case *packet.SetActorData:
-			pk.EntityMetadata = t.upgradeEntityMetadata(pk.EntityMetadata)
+			pk.EntityMetadata = t.upgradeEntityMetadata(pk.EntityMetadata, ids, stateOf(conn).isBlockEntity(pk.EntityRuntimeID))
+		case *packet.BlockActorData:
+			t.mapping.UpgradeBlockActorData(pk.NBTData)
 		}
 		result = append(result, pk)
 	}
@@ -250,67 +349,185 @@ func (t *DefaultBlockTranslator) DowngradeBlockRuntimeID(input uint32) uint32 {
 	if t.latest == t.mapping {
 		return input
 	}
-	state, ok := t.latest.RuntimeIDToState(input)
-	if !ok {
-		return t.mapping.Air()
-	}
-	runtimeID, ok := t.mapping.StateToRuntimeID(state)
-	if !ok {
-		return t.mapping.Air()
-	}
-	return runtimeID
+	return t.runtimeIDTable().downgradeRuntimeID(input)
 }
 
 func (t *DefaultBlockTranslator) DowngradeChunk(input *chunk.Chunk) *chunk.Chunk {
-	if t.latest == t.mapping {
+	return t.downgradeChunk(input, t.networkIDs(nil))
+}
+
+// downgradeChunk downgrades a chunk of which the blocks are held as the network IDs translated by ids.
+func (t *DefaultBlockTranslator) downgradeChunk(input *chunk.Chunk, ids blockNetworkIDs) *chunk.Chunk {
+	if t.latest == t.mapping && t.biomes == nil {
 		return input
 	}
 
 	start := 0
-	r := world.Overworld.Range()
+	r := input.Range()
 	if t.oldFormat {
 		start = 4
 		r = cube.Range{0, 255}
 	}
-	downgraded := chunk.New(t.mapping.Air(), r)
+	downgraded := chunk.New(ids.legacyAir, r)
 
-	i := 0
 	// First downgrade the blocks.
-	for _, sub := range input.Sub()[start : len(input.Sub())-start] {
-		t.DowngradeSubChunk(sub)
+	for i, sub := range input.Sub()[start : len(input.Sub())-start] {
+		t.downgradeSubChunk(sub, ids)
 		downgraded.Sub()[i] = sub
-		i += 1
 	}
-	i = 0
 	// Then downgrade the biome ids.
-	for _, sub := range input.BiomeSub()[start : len(input.BiomeSub())-start] {
-		// todo
-		sub.Palette().Replace(func(v uint32) uint32 {
-			return 0 // at least the client doesn't crash now
-		})
-		downgraded.BiomeSub()[i] = sub
-		i += 1
-	}
+	t.downgradeBiomes(input)
+	copy(downgraded.BiomeSub(), input.BiomeSub()[start:len(input.BiomeSub())-start])
 
 	return downgraded
 }
 
+// DowngradeBiomeID downgrades a biome ID of the latest version to a biome ID the legacy client knows. Biomes that
+// are newer than the client are replaced by their nearest equivalent. The ID is returned unchanged if the translator
+// has no biome mapping.
+func (t *DefaultBlockTranslator) DowngradeBiomeID(input uint32) uint32 {
+	if t.biomes == nil {
+		return input
+	}
+	name, ok := t.latestBiomes.BiomeIDToName(input)
+	if !ok {
+		// Not a vanilla biome, so it was most likely defined by the server.
+		return input
+	}
+	id, _ := t.biomes.BiomeNameToID(downgradeBiomeName(t.biomes, name))
+	return id
+}
+
+// downgradeBiomes downgrades the biome IDs of all biome storages of the chunk passed.
+func (t *DefaultBlockTranslator) downgradeBiomes(c *chunk.Chunk) {
+	var previous *chunk.PalettedStorage
+	for _, sub := range c.BiomeSub() {
+		if sub == previous {
+			// Storages equal to the previous one are shared, so don't downgrade them twice.
+			continue
+		}
+		sub.Palette().Replace(t.DowngradeBiomeID)
+		previous = sub
+	}
+}
+
+// downgradeBiomePayload downgrades the biomes at the start of the LevelChunk payload passed, which are sent when
+// sub chunks are requested separately. The rest of the payload is kept as is.
+func (t *DefaultBlockTranslator) downgradeBiomePayload(payload []byte, r cube.Range) []byte {
+	buf := bytes.NewBuffer(payload)
+	biomes, err := chunk.NetworkDecodeBiomes(t.latest.Air(), buf, r, t.lpse)
+	if err != nil {
+		return payload
+	}
+	t.downgradeBiomes(biomes)
+	return append(chunk.EncodeBiomes(biomes, chunk.NetworkEncoding), buf.Bytes()...)
+}
+
 func (t *DefaultBlockTranslator) DowngradeSubChunk(input *chunk.SubChunk) {
-	if t.latest == t.mapping {
+	t.downgradeSubChunk(input, t.networkIDs(nil))
+}
+
+// downgradeSubChunk downgrades a sub chunk of which the blocks are held as the network IDs translated by ids.
+func (t *DefaultBlockTranslator) downgradeSubChunk(input *chunk.SubChunk, ids blockNetworkIDs) {
+	if ids.table == nil {
 		return
 	}
 	for _, storage := range input.Layers() {
-		storage.Palette().Replace(t.DowngradeBlockRuntimeID)
+		storage.Palette().Replace(ids.downgrade)
 	}
 }
 
-func (t *DefaultBlockTranslator) downgradeEntityMetadata(metadata map[uint32]any) map[uint32]any {
+// downgradeChunkTail downgrades the border blocks and block actors that follow the sub chunks in the LevelChunk
+// payload passed, returning the downgraded bytes. The block function returns the runtime ID of the block at a
+// position in the chunk, if known.
+func (t *DefaultBlockTranslator) downgradeChunkTail(buf *bytes.Buffer, block func(x, y, z int32) (uint32, bool)) []byte {
+	safeBytes := buf.Bytes()
+
+	countBorder, err := buf.ReadByte()
+	if err != nil {
+		return safeBytes
+	}
+	borderBytes := make([]byte, countBorder)
+	if _, err = buf.Read(borderBytes); err != nil {
+		return safeBytes
+	}
+	writeBuf := bytes.NewBuffer(nil)
+	writeBuf.WriteByte(countBorder)
+	writeBuf.Write(borderBytes)
+
+	t.downgradeBlockActors(buf, writeBuf, block)
+	return append(writeBuf.Bytes(), buf.Bytes()...)
+}
+
+// downgradeBlockActors reads block actors from buf until it is exhausted, downgrades them and writes them to
+// writeBuf. Block actors that don't exist in the legacy version are left out.
+func (t *DefaultBlockTranslator) downgradeBlockActors(buf, writeBuf *bytes.Buffer, block func(x, y, z int32) (uint32, bool)) {
+	enc := nbt.NewEncoderWithEncoding(writeBuf, nbt.NetworkLittleEndian)
+	dec := nbt.NewDecoderWithEncoding(buf, nbt.NetworkLittleEndian)
+	for {
+		var decNbt map[string]any
+		if err := dec.Decode(&decNbt); err != nil {
+			break
+		}
+		if t.latest != t.mapping {
+			t.fillSkullType(decNbt, block)
+		}
+		t.mapping.DowngradeBlockActorData(decNbt)
+		if len(decNbt) == 0 {
+			continue
+		}
+
+		if err := enc.Encode(decNbt); err != nil {
+			break
+		}
+	}
+}
+
+// fillSkullType sets the SkullType of a Skull block actor, which is no longer set by the latest version, to the
+// type of the skull block at its position.
+func (t *DefaultBlockTranslator) fillSkullType(actor map[string]any, block func(x, y, z int32) (uint32, bool)) {
+	if id, _ := actor["id"].(string); id != "Skull" {
+		return
+	}
+	if skullType, ok := actor["SkullType"].(uint8); ok && skullType != 255 {
+		return
+	}
+	x, _ := actor["x"].(int32)
+	y, _ := actor["y"].(int32)
+	z, _ := actor["z"].(int32)
+	rid, ok := block(x, y, z)
+	if !ok {
+		return
+	}
+	if state, ok := t.latest.RuntimeIDToState(rid); ok {
+		if skullType, ok := skullTypes[state.Name]; ok {
+			actor["SkullType"] = skullType
+		}
+	}
+}
+
+// encodeSubChunk encodes a downgraded sub chunk. The range passed must be the one the sub chunk was decoded with.
+func (t *DefaultBlockTranslator) encodeSubChunk(sub *chunk.SubChunk, r cube.Range, ind int) []byte {
+	return chunk.EncodeSubChunk(sub, chunk.NetworkEncoding, t.pe, chunk.SubChunkVersion9, r, ind)
+}
+
+// downgradeBlockChangeEntries downgrades the runtime IDs of the entries passed.
+func (t *DefaultBlockTranslator) downgradeBlockChangeEntries(entries []protocol.BlockChangeEntry, ids blockNetworkIDs) []protocol.BlockChangeEntry {
+	downgraded := make([]protocol.BlockChangeEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.BlockRuntimeID = ids.downgrade(entry.BlockRuntimeID)
+		downgraded = append(downgraded, entry)
+	}
+	return downgraded
+}
+
+// downgradeEntityMetadata downgrades the block network IDs held in entity metadata. The variant of the entity is
+// only a block network ID if blockVariant is true, as it is for falling blocks.
+func (t *DefaultBlockTranslator) downgradeEntityMetadata(metadata map[uint32]any, ids blockNetworkIDs, blockVariant bool) map[uint32]any {
 	if t.latest == t.mapping {
 		return metadata
 	}
-	if latestRID, ok := metadata[protocol.EntityDataKeyVariant]; ok {
-		metadata[protocol.EntityDataKeyVariant] = int32(t.DowngradeBlockRuntimeID(uint32(latestRID.(int32))))
-	}
+	translateBlockEntityData(metadata, ids.downgrade, blockVariant)
 	return metadata
 }
 
@@ -318,23 +535,73 @@ func (t *DefaultBlockTranslator) UpgradeBlockRuntimeID(input uint32) uint32 {
 	if t.latest == t.mapping {
 		return input
 	}
-	state, ok := t.mapping.RuntimeIDToState(input)
-	if !ok {
-		return t.latest.Air()
+	return t.runtimeIDTable().upgradeRuntimeID(input)
+}
+
+// networkIDs returns the blockNetworkIDs used to translate the block network IDs sent over the connection passed.
+func (t *DefaultBlockTranslator) networkIDs(conn *minecraft.Conn) blockNetworkIDs {
+	ids := blockNetworkIDs{hashed: stateOf(conn).usesHashedBlockIDs(), latestAir: t.latest.Air(), legacyAir: t.mapping.Air()}
+	if ids.hashed {
+		ids.latestAir, ids.legacyAir = airHash, airHash
 	}
-	runtimeID, ok := t.latest.StateToRuntimeID(state)
-	if !ok {
-		return t.latest.Air()
+	if t.latest != t.mapping {
+		ids.table = t.runtimeIDTable()
+	}
+	return ids
+}
+
+// blobFingerprint returns a hash of the runtime ID translation of the translator, which includes the custom blocks
+// the mappings were adjusted for and the substitutions of missing blocks.
+func (t *DefaultBlockTranslator) blobFingerprint() uint64 {
+	return t.runtimeIDTable().blobFingerprint()
+}
+
+// runtimeIDTable returns the runtime ID tables between the mappings of the translator, building them if they
+// don't exist yet or are outdated.
+func (t *DefaultBlockTranslator) runtimeIDTable() *runtimeIDTable {
+	table := t.tables.Load()
+	if table == nil || !table.upToDate(t.mapping, t.latest) {
+		t.substitutionsMu.Lock()
+		substitutions := t.substitutions
+		t.substitutionsMu.Unlock()
+
+		table = newRuntimeIDTable(t.mapping, t.latest, substitutions)
+		t.tables.Store(table)
 	}
-	return runtimeID
+	return table
 }
 
-func (t *DefaultBlockTranslator) upgradeEntityMetadata(metadata map[uint32]any) map[uint32]any {
+// upgradeEntityMetadata upgrades the block network IDs held in entity metadata. The variant of the entity is only
+// a block network ID if blockVariant is true, as it is for falling blocks.
+func (t *DefaultBlockTranslator) upgradeEntityMetadata(metadata map[uint32]any, ids blockNetworkIDs, blockVariant bool) map[uint32]any {
 	if t.latest == t.mapping {
 		return metadata
 	}
-	if latestRID, ok := metadata[protocol.EntityDataKeyVariant]; ok {
-		metadata[protocol.EntityDataKeyVariant] = int32(t.UpgradeBlockRuntimeID(uint32(latestRID.(int32))))
-	}
+	translateBlockEntityData(metadata, ids.upgrade, blockVariant)
 	return metadata
 }
+
+// blockEntityDataKeys holds the entity data keys of which the values are block network IDs for all entities, such
+// as the block displayed in a minecart and the block carried by an enderman.
+var blockEntityDataKeys = []uint32{
+	protocol.EntityDataKeyDisplayTileRuntimeID,
+	protocol.EntityDataKeyCarryBlockRuntimeID,
+}
+
+// translateBlockEntityData translates the block network IDs held in entity metadata using the function passed. The
+// type of the values is kept, and values of other types, such as the firework item sent in place of a display
+// block, are left alone.
+func translateBlockEntityData(metadata map[uint32]any, translate func(uint32) uint32, blockVariant bool) {
+	keys := blockEntityDataKeys
+	if blockVariant {
+		keys = append(slices.Clip(keys), protocol.EntityDataKeyVariant)
+	}
+	for _, key := range keys {
+		switch v := metadata[key].(type) {
+		case int32:
+			metadata[key] = int32(translate(uint32(v)))
+		case int16:
+			metadata[key] = int16(translate(uint32(v)))
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/legacyver/legacypacket"
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// CameraReport describes how a camera preset or instruction was changed for a client of a version that doesn't
+// support all of it.
+type CameraReport struct {
+	// Preset is the name of the camera preset the report is about. For instructions, it is the name of the preset
+	// set by the instruction, if any.
+	Preset string
+	// Instruction is the camera instruction the report is about. It is nil for reports about presets.
+	Instruction *packet.CameraInstruction
+	// Dropped holds the names of the fields that were removed because the version doesn't support them. For
+	// instructions setting a preset, it also holds the fields that were removed from the preset.
+	Dropped []string
+	// Approximated holds the names of the fields that were replaced by the closest value the version supports.
+	Approximated []string
+}
+
+// CameraReporter is called with a CameraReport whenever a camera preset or instruction can't be sent to a client
+// of the version of a Protocol as it is. It returns the packets sent in addition to the changed preset or
+// instruction, such as SetTitle, PlayerFog or CameraShake packets that stand in for what was dropped. A reporter
+// overrides the built-in emulation of the protocol, so returning no packets sends the changed preset or instruction
+// only.
+type CameraReporter func(report CameraReport, conn *minecraft.Conn) []packet.Packet
+
+// SetCameraReporter sets the CameraReporter called for camera presets and instructions that can't be sent to
+// clients of the version of the protocol as they are, overriding the built-in emulation. Passing a nil reporter
+// removes the reporter, so that the built-in emulation is used again.
+func (p *Protocol) SetCameraReporter(reporter CameraReporter) {
+	p.cameraMu.Lock()
+	defer p.cameraMu.Unlock()
+	p.cameraReporter = reporter
+}
+
+// reportCamera passes the report to the CameraReporter of the protocol and returns the packets it returned. False is
+// returned if the protocol has no reporter, in which case the built-in emulation should be used.
+func (p *Protocol) reportCamera(report CameraReport, conn *minecraft.Conn) ([]packet.Packet, bool) {
+	p.cameraMu.Lock()
+	reporter := p.cameraReporter
+	p.cameraMu.Unlock()
+	if reporter == nil {
+		return nil, false
+	}
+	return reporter(report, conn), true
+}
+
+// cameraPresetParents holds the vanilla camera presets added since the oldest supported version, with the protocol
+// version in which each preset was added and the closest preset that exists before it.
+var cameraPresetParents = []struct {
+	version       int32
+	name, closest string
+}{
+	{version: proto.ID685, name: "minecraft:follow_orbit", closest: "minecraft:third_person"},
+	{version: proto.ID748, name: "minecraft:fixed_boom", closest: "minecraft:follow_orbit"},
+}
+
+// downgradeCameraParent returns the closest vanilla camera preset to the one passed that exists in the protocol
+// version passed.
+func downgradeCameraParent(id int32, parent string) string {
+	for i := len(cameraPresetParents) - 1; i >= 0; i-- {
+		if preset := cameraPresetParents[i]; preset.version > id && preset.name == parent {
+			parent = preset.closest
+		}
+	}
+	return parent
+}
+
+// dropCameraField unsets an optional field of a camera preset if the protocol version passed is older than the
+// version in which the field was added, and adds the name of the field to dropped if it was set.
+func dropCameraField[T any](id, version int32, name string, field *protocol.Optional[T], dropped *[]string) {
+	if id >= version {
+		return
+	}
+	if _, ok := field.Value(); ok {
+		*dropped = append(*dropped, name)
+	}
+	*field = protocol.Optional[T]{}
+}
+
+// downgradeCameraPreset turns the camera preset passed into the closest preset the protocol version passed
+// supports. The report returned describes the changes made, if any.
+func downgradeCameraPreset(id int32, preset protocol.CameraPreset) (protocol.CameraPreset, CameraReport) {
+	report := CameraReport{Preset: preset.Name}
+	if parent := downgradeCameraParent(id, preset.Parent); parent != preset.Parent {
+		preset.Parent = parent
+		report.Approximated = append(report.Approximated, "Parent")
+	}
+	// Older versions only know the view offset of orbiting cameras, which is the closest to an entity offset.
+	if offset, ok := preset.EntityOffset.Value(); ok && id < proto.ID729 {
+		if _, ok := preset.ViewOffset.Value(); ok {
+			report.Dropped = append(report.Dropped, "EntityOffset")
+		} else {
+			preset.ViewOffset = protocol.Option(mgl32.Vec2{offset.X(), offset.Y()})
+			report.Approximated = append(report.Approximated, "EntityOffset")
+		}
+		preset.EntityOffset = protocol.Optional[mgl32.Vec3]{}
+	}
+	dropCameraField(id, proto.ID729, "RotationSpeed", &preset.RotationSpeed, &report.Dropped)
+	dropCameraField(id, proto.ID729, "SnapToTarget", &preset.SnapToTarget, &report.Dropped)
+	dropCameraField(id, proto.ID748, "HorizontalRotationLimit", &preset.HorizontalRotationLimit, &report.Dropped)
+	dropCameraField(id, proto.ID748, "VerticalRotationLimit", &preset.VerticalRotationLimit, &report.Dropped)
+	dropCameraField(id, proto.ID748, "ContinueTargeting", &preset.ContinueTargeting, &report.Dropped)
+	dropCameraField(id, proto.ID748, "AlignTargetAndCameraForward", &preset.AlignTargetAndCameraForward, &report.Dropped)
+	dropCameraField(id, proto.ID766, "TrackingRadius", &preset.TrackingRadius, &report.Dropped)
+	dropCameraField(id, proto.ID766, "AimAssist", &preset.AimAssist, &report.Dropped)
+	return preset, report
+}
+
+// downgradeCameraInstruction removes the parts of the camera instruction passed that the protocol version passed
+// doesn't support. The changes made to the preset set by the instruction are taken from the presets passed, which
+// hold the reports of the presets last sent to the client.
+func downgradeCameraInstruction(id int32, pk *packet.CameraInstruction, presets []CameraReport) (*packet.CameraInstruction, CameraReport) {
+	instruction := *pk
+	report := CameraReport{Instruction: pk}
+	if set, ok := instruction.Set.Value(); ok && int(set.Preset) < len(presets) {
+		preset := presets[set.Preset]
+		report.Preset = preset.Preset
+		report.Dropped = append(report.Dropped, preset.Dropped...)
+		report.Approximated = append(report.Approximated, preset.Approximated...)
+	}
+	// Aim assist targets only exist since 1.21.20, and there is nothing close to them before.
+	dropCameraField(id, proto.ID712, "Target", &instruction.Target, &report.Dropped)
+	dropCameraField(id, proto.ID712, "RemoveTarget", &instruction.RemoveTarget, &report.Dropped)
+	return &instruction, report
+}
+
+// emptyCameraInstruction checks if the camera instruction passed doesn't instruct anything.
+func emptyCameraInstruction(pk *packet.CameraInstruction) bool {
+	_, set := pk.Set.Value()
+	_, cleared := pk.Clear.Value()
+	_, fade := pk.Fade.Value()
+	_, target := pk.Target.Value()
+	_, removeTarget := pk.RemoveTarget.Value()
+	return !set && !cleared && !fade && !target && !removeTarget
+}
+
+// emulateCameras turns the camera presets and instructions of the packets passed into the closest ones the version
+// of the protocol supports. The changes are reported to the CameraReporter of the protocol, and the packets it
+// returns are sent after the preset or instruction. Without a reporter, the packets of the built-in emulation are
+// sent instead. The packets passed are not modified, as they may be shared with other connections.
+func (p *Protocol) emulateCameras(pks []packet.Packet, conn *minecraft.Conn) []packet.Packet {
+	if p.id >= proto.ID766 {
+		return pks
+	}
+	state := stateOf(conn)
+	result := make([]packet.Packet, 0, len(pks))
+	for _, pk := range pks {
+		switch pk := pk.(type) {
+		case *packet.CameraPresets:
+			// The presets are encoded in the layout of the version here, so that they aren't converted again later.
+			presets := make([]proto.CameraPreset, len(pk.Presets))
+			reports := make([]CameraReport, len(pk.Presets))
+			for i, preset := range pk.Presets {
+				var downgraded protocol.CameraPreset
+				downgraded, reports[i] = downgradeCameraPreset(p.id, preset)
+				presets[i] = (&proto.CameraPreset{}).FromLatest(downgraded)
+			}
+			state.setCameraPresets(reports)
+			result = append(result, &legacypacket.CameraPresets{Presets: presets})
+			for _, report := range reports {
+				if !report.changed() {
+					continue
+				}
+				// The changes made to presets are approximated in the presets themselves, so there is nothing
+				// left for the built-in emulation to send.
+				reported, _ := p.reportCamera(report, conn)
+				result = append(result, reported...)
+			}
+		case *packet.CameraInstruction:
+			if cleared, ok := pk.Clear.Value(); ok && cleared {
+				state.setCameraSet(nil)
+			}
+			if set, ok := pk.Set.Value(); ok {
+				state.setCameraSet(&set)
+			}
+			instruction, report := downgradeCameraInstruction(p.id, pk, state.cameraPresets())
+			if !emptyCameraInstruction(instruction) {
+				result = append(result, instruction)
+			}
+			if !report.changed() {
+				continue
+			}
+			if reported, ok := p.reportCamera(report, conn); ok {
+				result = append(result, reported...)
+			} else {
+				result = append(result, p.emulateCameraInstruction(pk, state)...)
+			}
+		default:
+			if p.id < proto.ID712 {
+				trackEntityPosition(pk, state)
+			}
+			result = append(result, pk)
+		}
+	}
+	return result
+}
+
+// emulateCameraInstruction returns the packets that stand in for the parts of the camera instruction passed that
+// the version of the protocol doesn't support. Versions that can't target entities have the camera set last
+// face the position of the target instead. Unlike a target, the camera doesn't follow the entity as it moves.
+func (p *Protocol) emulateCameraInstruction(pk *packet.CameraInstruction, state *connState) []packet.Packet {
+	if p.id >= proto.ID712 {
+		return nil
+	}
+	set, ok := state.lastCameraSet()
+	if !ok {
+		// Facing is part of a set instruction, so there is nothing to face the target with.
+		return nil
+	}
+	// The camera is already in place, so it shouldn't ease into it again.
+	set.Ease = protocol.Optional[protocol.CameraEase]{}
+	if target, ok := pk.Target.Value(); ok {
+		pos, ok := state.position(target.EntityUniqueID)
+		if !ok {
+			return nil
+		}
+		offset, _ := target.CenterOffset.Value()
+		set.Facing = protocol.Option(pos.Add(offset))
+		return []packet.Packet{&packet.CameraInstruction{Set: protocol.Option(set)}}
+	}
+	if removed, ok := pk.RemoveTarget.Value(); ok && removed {
+		// Sending the last set instruction again undoes the facing of the target.
+		return []packet.Packet{&packet.CameraInstruction{Set: protocol.Option(set)}}
+	}
+	return nil
+}
+
+// trackEntityPosition updates the positions of entities kept in the state passed with the packet passed. The
+// position of the player itself is only known from StartGame and MovePlayer packets, as the movement it sends
+// itself isn't tracked.
+func trackEntityPosition(pk packet.Packet, state *connState) {
+	switch pk := pk.(type) {
+	case *packet.StartGame:
+		state.addPosition(pk.EntityUniqueID, pk.EntityRuntimeID, pk.PlayerPosition)
+	case *packet.AddActor:
+		state.addPosition(pk.EntityUniqueID, pk.EntityRuntimeID, pk.Position)
+	case *packet.AddPlayer:
+		state.addPosition(pk.AbilityData.EntityUniqueID, pk.EntityRuntimeID, pk.Position)
+	case *packet.MoveActorAbsolute:
+		state.movePosition(pk.EntityRuntimeID, func(mgl32.Vec3) mgl32.Vec3 {
+			return pk.Position
+		})
+	case *packet.MovePlayer:
+		state.movePosition(pk.EntityRuntimeID, func(mgl32.Vec3) mgl32.Vec3 {
+			return pk.Position
+		})
+	case *packet.MoveActorDelta:
+		state.movePosition(pk.EntityRuntimeID, func(pos mgl32.Vec3) mgl32.Vec3 {
+			for i, flag := range []uint16{packet.MoveActorDeltaFlagHasX, packet.MoveActorDeltaFlagHasY, packet.MoveActorDeltaFlagHasZ} {
+				if pk.Flags&flag != 0 {
+					pos[i] = pk.Position[i]
+				}
+			}
+			return pos
+		})
+	case *packet.RemoveActor:
+		state.removePosition(pk.EntityUniqueID)
+	}
+}
+
+// changed checks if the report holds any changes.
+func (r CameraReport) changed() bool {
+	return len(r.Dropped) != 0 || len(r.Approximated) != 0
+}
@@ -0,0 +1,103 @@
+package legacyver
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/akmalfairuz/legacy-version/legacyver/legacypacket"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestCameraPresetsDowngradedOnce(t *testing.T) {
+	p, conn := New712(), &minecraft.Conn{}
+	latest := &packet.CameraPresets{Presets: []protocol.CameraPreset{{
+		Name:         "test:camera",
+		Parent:       "minecraft:fixed_boom",
+		EntityOffset: protocol.Option(mgl32.Vec3{1, 2, 3}),
+		AimAssist:    protocol.Option(protocol.CameraPresetAimAssist{}),
+	}}}
+
+	pks := p.ConvertFromLatest(latest, conn)
+	if len(pks) != 1 {
+		t.Fatalf("got %v packets, want 1", len(pks))
+	}
+	presets, ok := pks[0].(*legacypacket.CameraPresets)
+	if !ok {
+		t.Fatalf("got packet %T, want *legacypacket.CameraPresets", pks[0])
+	}
+	preset := presets.Presets[0]
+	if preset.Parent != "minecraft:follow_orbit" {
+		t.Errorf("parent = %v, want minecraft:follow_orbit", preset.Parent)
+	}
+	if offset, _ := preset.ViewOffset.Value(); offset != (mgl32.Vec2{1, 2}) {
+		t.Errorf("view offset = %v, want [1 2]", offset)
+	}
+	if _, ok := preset.AimAssist.Value(); ok {
+		t.Error("aim assist was not dropped")
+	}
+	if latest.Presets[0].Parent != "minecraft:fixed_boom" {
+		t.Error("presets of the latest packet were modified")
+	}
+}
+
+// targetCamera sends an actor, a camera set instruction and a camera instruction targeting the actor through the
+// protocol passed, and returns the packets the target instruction was converted to.
+func targetCamera(p *Protocol, conn *minecraft.Conn) []packet.Packet {
+	p.ConvertFromLatest(&packet.AddActor{EntityUniqueID: 5, EntityRuntimeID: 5, EntityType: "minecraft:zombie", Position: mgl32.Vec3{10, 64, 10}}, conn)
+	p.ConvertFromLatest(&packet.CameraInstruction{Set: protocol.Option(protocol.CameraInstructionSet{Preset: 0})}, conn)
+	return p.ConvertFromLatest(&packet.CameraInstruction{Target: protocol.Option(protocol.CameraInstructionTarget{
+		EntityUniqueID: 5,
+		CenterOffset:   protocol.Option(mgl32.Vec3{0, 1, 0}),
+	})}, conn)
+}
+
+func TestCameraTargetEmulation(t *testing.T) {
+	pks := targetCamera(New686(), &minecraft.Conn{})
+	if len(pks) != 1 {
+		t.Fatalf("got %v packets, want 1", len(pks))
+	}
+	instruction, ok := pks[0].(*legacypacket.CameraInstruction)
+	if !ok {
+		t.Fatalf("got packet %T, want *legacypacket.CameraInstruction", pks[0])
+	}
+	set, ok := instruction.Set.Value()
+	if !ok {
+		t.Fatal("target was not emulated with a set instruction")
+	}
+	if facing, _ := set.Facing.Value(); facing != (mgl32.Vec3{10, 65, 10}) {
+		t.Errorf("facing = %v, want [10 65 10]", facing)
+	}
+}
+
+func TestCameraReporterOverridesEmulation(t *testing.T) {
+	p := New686()
+	var reports []CameraReport
+	p.SetCameraReporter(func(report CameraReport, conn *minecraft.Conn) []packet.Packet {
+		reports = append(reports, report)
+		return []packet.Packet{&packet.SetTitle{ActionType: packet.TitleActionSetTitle, Text: "target"}}
+	})
+
+	pks := targetCamera(p, &minecraft.Conn{})
+	if len(reports) != 1 || !slices.Contains(reports[0].Dropped, "Target") {
+		t.Fatalf("reports = %+v, want one report dropping Target", reports)
+	}
+	if len(pks) != 1 {
+		t.Fatalf("got %v packets, want 1", len(pks))
+	}
+	if _, ok := pks[0].(*legacypacket.SetTitle); !ok {
+		t.Fatalf("got packet %T, want the SetTitle of the reporter", pks[0])
+	}
+
+	// Removing the reporter brings back the built-in emulation.
+	p.SetCameraReporter(nil)
+	pks = targetCamera(p, &minecraft.Conn{})
+	if len(pks) != 1 {
+		t.Fatalf("got %v packets, want 1", len(pks))
+	}
+	if _, ok := pks[0].(*legacypacket.CameraInstruction); !ok {
+		t.Fatalf("got packet %T, want the emulated *legacypacket.CameraInstruction", pks[0])
+	}
+}
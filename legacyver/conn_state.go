@@ -0,0 +1,343 @@
+package legacyver
+
+import (
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// connState holds the state the translators keep for a single connection.
+type connState struct {
+	mu sync.Mutex
+	// dimension is the dimension the player is currently in.
+	dimension int32
+	// ranges holds the height ranges of dimensions that were changed by the server through the DimensionData
+	// packet, indexed by dimension ID.
+	ranges map[int32]cube.Range
+	// hashedBlockIDs is true if the server enabled UseBlockNetworkIDHashes, so that blocks are sent as the hashes of
+	// their states rather than their runtime IDs.
+	hashedBlockIDs bool
+	// blockEntities holds the unique IDs of entities of which the variant is a block network ID, such as falling
+	// blocks, indexed by their runtime IDs.
+	blockEntities map[uint64]int64
+	// input holds the input flags of the last PlayerAuthInput packet sent by the player, in the layout of the
+	// latest version.
+	input protocol.Bitset
+	// presets holds the reports of the camera presets last sent to the player, in the order in which they were sent.
+	presets []CameraReport
+	// cameraSet holds the last camera set instruction sent to the player. It is nil if the camera of the player
+	// wasn't set or was cleared since.
+	cameraSet *protocol.CameraInstructionSet
+	// positions holds the last known positions of entities, indexed by their runtime IDs, and runtimeIDs holds the
+	// runtime IDs of entities indexed by their unique IDs. They are only kept for versions that can't target
+	// entities with the camera, so that the camera can face them instead.
+	positions  map[uint64]mgl32.Vec3
+	runtimeIDs map[int64]uint64
+	// forms holds how the custom forms open for the player were rewritten, indexed by their form IDs. Forms of
+	// which the elements weren't rewritten aren't included.
+	forms map[uint32]*formTranslation
+	// blobs holds the hashes of the blobs sent to the player, indexed by the hashes the server knows them by, and
+	// translatedBlobs holds the opposite. Entries are removed once the player no longer refers to the blob.
+	blobs, translatedBlobs map[uint64]uint64
+	// biomeBlobs holds the hashes the server knows the blobs holding the biomes of a chunk by. Entries are removed
+	// together with the blob.
+	biomeBlobs map[uint64]struct{}
+}
+
+// connStates holds the connState of every connection, indexed by the address of the connection. The address is
+// used rather than the connection itself, so that the map doesn't keep the connection from being garbage
+// collected. Entries are removed by forgetConn once it is.
+var connStates sync.Map
+
+// stateOf returns the connState of the connection passed, creating it if it doesn't exist yet.
+func stateOf(conn *minecraft.Conn) *connState {
+	if conn == nil {
+		return &connState{}
+	}
+	key := uintptr(unsafe.Pointer(conn))
+	if s, ok := connStates.Load(key); ok {
+		return s.(*connState)
+	}
+	s, loaded := connStates.LoadOrStore(key, &connState{})
+	if !loaded {
+		// The connection doesn't expose when it is closed, but it is garbage collected once nothing uses it anymore,
+		// which includes the translators. Its address can't be reused before the finalizer has run.
+		runtime.SetFinalizer(conn, forgetConn)
+	}
+	return s.(*connState)
+}
+
+// forgetConn removes the state the translators keep for the connection passed.
+func forgetConn(conn *minecraft.Conn) {
+	connStates.Delete(uintptr(unsafe.Pointer(conn)))
+}
+
+// setDimension sets the dimension the player is currently in.
+func (s *connState) setDimension(dimension int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dimension = dimension
+}
+
+// setHashedBlockIDs sets if block network IDs are hashes of the block states.
+func (s *connState) setHashedBlockIDs(hashed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hashedBlockIDs = hashed
+}
+
+// usesHashedBlockIDs checks if block network IDs are hashes of the block states.
+func (s *connState) usesHashedBlockIDs() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hashedBlockIDs
+}
+
+// addBlockEntity marks the entity passed as an entity of which the variant is a block network ID.
+func (s *connState) addBlockEntity(uniqueID int64, runtimeID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blockEntities == nil {
+		s.blockEntities = make(map[uint64]int64)
+	}
+	s.blockEntities[runtimeID] = uniqueID
+}
+
+// removeEntity forgets about the entity with the unique ID passed.
+func (s *connState) removeEntity(uniqueID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for runtimeID, id := range s.blockEntities {
+		if id == uniqueID {
+			delete(s.blockEntities, runtimeID)
+		}
+	}
+}
+
+// isBlockEntity checks if the variant of the entity with the runtime ID passed is a block network ID.
+func (s *connState) isBlockEntity(runtimeID uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.blockEntities[runtimeID]
+	return ok
+}
+
+// setInput sets the input flags of the last PlayerAuthInput packet sent by the player.
+func (s *connState) setInput(input protocol.Bitset) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.input = input
+}
+
+// lastInput returns the input flags of the last PlayerAuthInput packet sent by the player. The flags returned are
+// empty if the player didn't send any input yet.
+func (s *connState) lastInput() protocol.Bitset {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.input
+}
+
+// setCameraPresets sets the reports of the camera presets last sent to the player.
+func (s *connState) setCameraPresets(presets []CameraReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.presets = presets
+}
+
+// cameraPresets returns the reports of the camera presets last sent to the player.
+func (s *connState) cameraPresets() []CameraReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.presets
+}
+
+// setCameraSet sets the last camera set instruction sent to the player. Passing nil clears it.
+func (s *connState) setCameraSet(set *protocol.CameraInstructionSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cameraSet = set
+}
+
+// lastCameraSet returns the last camera set instruction sent to the player, if it is still active.
+func (s *connState) lastCameraSet() (protocol.CameraInstructionSet, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cameraSet == nil {
+		return protocol.CameraInstructionSet{}, false
+	}
+	return *s.cameraSet, true
+}
+
+// addPosition sets the position of the entity with the unique and runtime IDs passed.
+func (s *connState) addPosition(uniqueID int64, runtimeID uint64, pos mgl32.Vec3) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.positions == nil {
+		s.positions, s.runtimeIDs = make(map[uint64]mgl32.Vec3), make(map[int64]uint64)
+	}
+	s.positions[runtimeID], s.runtimeIDs[uniqueID] = pos, runtimeID
+}
+
+// movePosition passes the position of the entity with the runtime ID passed to f and stores the result. Entities of
+// which the position isn't known are ignored.
+func (s *connState) movePosition(runtimeID uint64, f func(pos mgl32.Vec3) mgl32.Vec3) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pos, ok := s.positions[runtimeID]; ok {
+		s.positions[runtimeID] = f(pos)
+	}
+}
+
+// removePosition forgets about the position of the entity with the unique ID passed.
+func (s *connState) removePosition(uniqueID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if runtimeID, ok := s.runtimeIDs[uniqueID]; ok {
+		delete(s.positions, runtimeID)
+		delete(s.runtimeIDs, uniqueID)
+	}
+}
+
+// position returns the last known position of the entity with the unique ID passed.
+func (s *connState) position(uniqueID int64) (mgl32.Vec3, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runtimeID, ok := s.runtimeIDs[uniqueID]
+	if !ok {
+		return mgl32.Vec3{}, false
+	}
+	pos, ok := s.positions[runtimeID]
+	return pos, ok
+}
+
+// setForm sets how the form with the ID passed was rewritten. A nil translation removes the form.
+func (s *connState) setForm(formID uint32, translation *formTranslation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if translation == nil {
+		delete(s.forms, formID)
+		return
+	}
+	if s.forms == nil {
+		s.forms = make(map[uint32]*formTranslation)
+	}
+	s.forms[formID] = translation
+}
+
+// takeForm returns and removes how the form with the ID passed was rewritten, if it was.
+func (s *connState) takeForm(formID uint32) (*formTranslation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	translation, ok := s.forms[formID]
+	delete(s.forms, formID)
+	return translation, ok
+}
+
+// addBlob remembers that the blob with the latest hash passed was sent to the player with the translated hash.
+func (s *connState) addBlob(latest, translated uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blobs == nil {
+		s.blobs, s.translatedBlobs = make(map[uint64]uint64), make(map[uint64]uint64)
+	}
+	s.blobs[latest], s.translatedBlobs[translated] = translated, latest
+}
+
+// latestBlob returns the latest hash of the blob sent to the player with the translated hash passed. If forget is
+// true, the blob is forgotten.
+func (s *connState) latestBlob(translated uint64, forget bool) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latest, ok := s.translatedBlobs[translated]
+	if ok && forget {
+		delete(s.blobs, latest)
+		delete(s.translatedBlobs, translated)
+		delete(s.biomeBlobs, latest)
+	}
+	return latest, ok
+}
+
+// translatedBlob returns the hash the blob with the latest hash passed was sent to the player with, and forgets the
+// blob.
+func (s *connState) translatedBlob(latest uint64) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	translated, ok := s.blobs[latest]
+	if ok {
+		delete(s.blobs, latest)
+		delete(s.translatedBlobs, translated)
+		delete(s.biomeBlobs, latest)
+	}
+	return translated, ok
+}
+
+// addBiomeBlob remembers that the blob with the latest hash passed holds the biomes of a chunk.
+func (s *connState) addBiomeBlob(latest uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.biomeBlobs == nil {
+		s.biomeBlobs = make(map[uint64]struct{})
+	}
+	s.biomeBlobs[latest] = struct{}{}
+}
+
+// isBiomeBlob checks if the blob with the latest hash passed holds the biomes of a chunk.
+func (s *connState) isBiomeBlob(latest uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.biomeBlobs[latest]
+	return ok
+}
+
+// setDimensionDefinitions overwrites the height ranges of the dimensions passed.
+func (s *connState) setDimensionDefinitions(definitions []protocol.DimensionDefinition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ranges == nil {
+		s.ranges = make(map[int32]cube.Range)
+	}
+	for _, def := range definitions {
+		if dimension, ok := dimensionIDs[def.Name]; ok {
+			s.ranges[dimension] = cube.Range{int(def.Range[0]), int(def.Range[1])}
+		}
+	}
+}
+
+// currentRange returns the height range of the dimension the player is currently in.
+func (s *connState) currentRange() cube.Range {
+	s.mu.Lock()
+	dimension := s.dimension
+	s.mu.Unlock()
+	return s.dimensionRange(dimension)
+}
+
+// dimensionRange returns the height range of the dimension passed. Ranges set through the DimensionData packet
+// take precedence over the vanilla ones.
+func (s *connState) dimensionRange(dimension int32) cube.Range {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.ranges[dimension]; ok {
+		return r
+	}
+	switch dimension {
+	case packet.DimensionNether:
+		return world.Nether.Range()
+	case packet.DimensionEnd:
+		return world.End.Range()
+	default:
+		return world.Overworld.Range()
+	}
+}
+
+// dimensionIDs maps the names of the dimensions used in the DimensionData packet to their IDs.
+var dimensionIDs = map[string]int32{
+	"minecraft:overworld": packet.DimensionOverworld,
+	"minecraft:nether":    packet.DimensionNether,
+	"minecraft:the_end":   packet.DimensionEnd,
+}
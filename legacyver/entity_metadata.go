@@ -0,0 +1,195 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"github.com/df-mc/dragonfly/server/entity/effect"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// entityMetadataSchema describes the changes made to entity metadata in a version of the game.
+type entityMetadataSchema struct {
+	// version is the protocol version in which the changes were made.
+	version int32
+	// addedKeys holds the keys that were added in the version. They are dropped for older clients.
+	addedKeys []uint32
+	// addedFlags holds the indices at which data flags were inserted in the version, in ascending order. For older
+	// clients these flags are removed and the flags after them are moved down.
+	addedFlags []uint8
+	// downgrade transforms the metadata of the version to that of the version before it, and upgrade does the
+	// opposite. Either may be nil if nothing needs to be done in that direction. downgrade is called before added
+	// keys are dropped, so that it may still convert them.
+	downgrade, upgrade func(metadata map[uint32]any)
+}
+
+// Data flags that were added after the last flag known to gophertunnel. They are appended to the end of the flags,
+// in the order in which they were added.
+const (
+	entityDataFlagBodyRotationBlocked = protocol.EntityDataFlagTimerFlag3 + 1 + iota
+	entityDataFlagRenderWhenInvisible
+	entityDataFlagBodyRotationAxisAligned
+	entityDataFlagCollidable
+	entityDataFlagWASDAirControlled
+	entityDataFlagDoesServerAuthOnlyDismount
+)
+
+// entityMetadataSchemas holds all changes made to entity metadata since the oldest supported version, ordered from
+// oldest to newest.
+var entityMetadataSchemas = []entityMetadataSchema{
+	{
+		version:    proto.ID685,
+		addedFlags: []uint8{entityDataFlagRenderWhenInvisible},
+	},
+	{
+		version:    proto.ID712,
+		addedFlags: []uint8{entityDataFlagBodyRotationAxisAligned, entityDataFlagCollidable, entityDataFlagWASDAirControlled},
+	},
+	{
+		// Mob effect particles are sent as a list of effect IDs since 1.21.40. Older clients only know the
+		// colour of the particles.
+		version:   proto.ID748,
+		addedKeys: []uint32{protocol.EntityDataKeyVisibleMobEffects},
+		downgrade: func(metadata map[uint32]any) {
+			if packed, ok := metadata[protocol.EntityDataKeyVisibleMobEffects].(int64); ok {
+				if colour, ambient, ok := visibleEffectColour(packed); ok {
+					metadata[protocol.EntityDataKeyEffectColor] = colour
+					metadata[protocol.EntityDataKeyEffectAmbience] = boolByte(ambient)
+				}
+			}
+		},
+	},
+	{
+		version:    proto.ID766,
+		addedFlags: []uint8{entityDataFlagDoesServerAuthOnlyDismount},
+	},
+}
+
+// downgradeEntityMetadata downgrades entity metadata of the latest version to the protocol version passed.
+func downgradeEntityMetadata(id int32, metadata map[uint32]any) map[uint32]any {
+	return downgradeEntityMetadataSchemas(entityMetadataSchemas, id, metadata)
+}
+
+// downgradeEntityMetadataSchemas downgrades entity metadata of the latest version to the protocol version passed
+// through the schemas passed, which are ordered from the oldest to the newest.
+func downgradeEntityMetadataSchemas(schemas []entityMetadataSchema, id int32, metadata map[uint32]any) map[uint32]any {
+	if metadata == nil {
+		return nil
+	}
+	for i := len(schemas) - 1; i >= 0; i-- {
+		schema := schemas[i]
+		if schema.version <= id {
+			continue
+		}
+		if schema.downgrade != nil {
+			schema.downgrade(metadata)
+		}
+		for _, key := range schema.addedKeys {
+			delete(metadata, key)
+		}
+		// Remove the highest flags first, so that the indices of the others don't change.
+		for j := len(schema.addedFlags) - 1; j >= 0; j-- {
+			index := schema.addedFlags[j]
+			updateEntityDataFlags(metadata, func(lo, hi uint64) (uint64, uint64) {
+				return removeFlag(lo, hi, index)
+			})
+		}
+	}
+	return metadata
+}
+
+// upgradeEntityMetadata upgrades entity metadata of the protocol version passed to the latest version.
+func upgradeEntityMetadata(id int32, metadata map[uint32]any) map[uint32]any {
+	return upgradeEntityMetadataSchemas(entityMetadataSchemas, id, metadata)
+}
+
+// upgradeEntityMetadataSchemas upgrades entity metadata of the protocol version passed to the latest version
+// through the schemas passed, which are ordered from the oldest to the newest.
+func upgradeEntityMetadataSchemas(schemas []entityMetadataSchema, id int32, metadata map[uint32]any) map[uint32]any {
+	if metadata == nil {
+		return nil
+	}
+	for _, schema := range schemas {
+		if schema.version <= id {
+			continue
+		}
+		for _, index := range schema.addedFlags {
+			updateEntityDataFlags(metadata, func(lo, hi uint64) (uint64, uint64) {
+				return insertFlag(lo, hi, index)
+			})
+		}
+		if schema.upgrade != nil {
+			schema.upgrade(metadata)
+		}
+	}
+	return metadata
+}
+
+// updateEntityDataFlags passes the data flags of the metadata passed to f as a single 128-bit set and stores the
+// result. The first 64 flags are held by EntityDataKeyFlags and the rest by EntityDataKeyFlagsTwo.
+func updateEntityDataFlags(metadata map[uint32]any, f func(lo, hi uint64) (uint64, uint64)) {
+	lo, hasLo := metadata[protocol.EntityDataKeyFlags].(int64)
+	hi, hasHi := metadata[protocol.EntityDataKeyFlagsTwo].(int64)
+	if !hasLo && !hasHi {
+		return
+	}
+	newLo, newHi := f(uint64(lo), uint64(hi))
+	if hasLo {
+		metadata[protocol.EntityDataKeyFlags] = int64(newLo)
+	}
+	if hasHi || newHi != 0 {
+		metadata[protocol.EntityDataKeyFlagsTwo] = int64(newHi)
+	}
+}
+
+// removeFlag removes the bit at the index passed from the 128-bit set, moving all bits after it down by one.
+func removeFlag(lo, hi uint64, index uint8) (uint64, uint64) {
+	if index >= 64 {
+		i := index - 64
+		below := hi & (1<<i - 1)
+		return lo, below | (hi>>(i+1))<<i
+	}
+	below := lo & (1<<index - 1)
+	lo = below | (lo>>(index+1))<<index | (hi&1)<<63
+	return lo, hi >> 1
+}
+
+// insertFlag inserts an unset bit at the index passed in the 128-bit set, moving all bits from it up by one.
+func insertFlag(lo, hi uint64, index uint8) (uint64, uint64) {
+	if index >= 64 {
+		i := index - 64
+		below := hi & (1<<i - 1)
+		return lo, below | (hi>>i)<<(i+1)
+	}
+	below := lo & (1<<index - 1)
+	hi = hi<<1 | lo>>63
+	return below | (lo>>index)<<(index+1), hi
+}
+
+// visibleEffectColour computes the particle colour of the effects packed in a VisibleMobEffects value, as sent to
+// older clients through EffectColor. The colour is the average colour of the effects, and the effects are ambient
+// if all of them are.
+func visibleEffectColour(packed int64) (colour int32, ambient bool, ok bool) {
+	var r, g, b, a, n int
+	ambient = true
+	for v := uint64(packed); v != 0; v >>= 7 {
+		t, found := effect.ByID(int(v&0x7f) >> 1)
+		if !found {
+			continue
+		}
+		c := t.RGBA()
+		r, g, b, a, n = r+int(c.R), g+int(c.G), b+int(c.B), a+int(c.A), n+1
+		ambient = ambient && v&1 == 1
+	}
+	if n == 0 {
+		return 0, false, false
+	}
+	// EffectColor holds the colour as ARGB.
+	return int32(uint32(a/n)<<24 | uint32(r/n)<<16 | uint32(g/n)<<8 | uint32(b/n)), ambient, true
+}
+
+// boolByte returns 1 if b is true and 0 otherwise.
+func boolByte(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package legacyver
+
+import (
+	"testing"
+
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+func TestEntityMetadataFlagRoundTrip(t *testing.T) {
+	// Flags inserted in the middle of both halves of the set, and at the boundary between them, move every flag
+	// after them.
+	schemas := []entityMetadataSchema{
+		{version: proto.ID712, addedFlags: []uint8{10, 63}},
+		{version: proto.ID748, addedFlags: []uint8{64, 100}},
+	}
+
+	lo := uint64(1<<9 | 1<<11 | 1<<62 | 1<<63)
+	latest := map[uint32]any{
+		protocol.EntityDataKeyFlags:    int64(lo),
+		protocol.EntityDataKeyFlagsTwo: int64(1<<1 | 1<<35 | 1<<37),
+	}
+	// Flags 10, 63, 64 and 100 don't exist in 1.21.2, so 63 is dropped and 9, 11, 62, 65, 99 and 101 become 9, 10,
+	// 61, 62, 96 and 97.
+	legacy := downgradeEntityMetadataSchemas(schemas, proto.ID686, copyMetadata(latest))
+	wantLo, wantHi := uint64(1<<9|1<<10|1<<61|1<<62), uint64(1<<32|1<<33)
+	if lo, hi := uint64(legacy[protocol.EntityDataKeyFlags].(int64)), uint64(legacy[protocol.EntityDataKeyFlagsTwo].(int64)); lo != wantLo || hi != wantHi {
+		t.Fatalf("downgraded flags = %x %x, want %x %x", lo, hi, wantLo, wantHi)
+	}
+
+	upgraded := upgradeEntityMetadataSchemas(schemas, proto.ID686, legacy)
+	wantLo, wantHi = 1<<9|1<<11|1<<62, 1<<1|1<<35|1<<37
+	if lo, hi := uint64(upgraded[protocol.EntityDataKeyFlags].(int64)), uint64(upgraded[protocol.EntityDataKeyFlagsTwo].(int64)); lo != wantLo || hi != wantHi {
+		t.Fatalf("upgraded flags = %x %x, want %x %x", lo, hi, wantLo, wantHi)
+	}
+}
+
+func TestEntityMetadataAddedFlags(t *testing.T) {
+	flags := uint64(1<<(protocol.EntityDataFlagTimerFlag3-64) | 1<<(entityDataFlagBodyRotationBlocked-64) |
+		1<<(entityDataFlagRenderWhenInvisible-64) | 1<<(entityDataFlagCollidable-64) |
+		1<<(entityDataFlagDoesServerAuthOnlyDismount-64))
+	metadata := downgradeEntityMetadata(proto.ID671, map[uint32]any{
+		protocol.EntityDataKeyFlags:    int64(1 << protocol.EntityDataFlagOnFire),
+		protocol.EntityDataKeyFlagsTwo: int64(flags),
+	})
+	// The flags added since 1.20.80 are at the end of the set, so the others keep their index.
+	want := uint64(1<<(protocol.EntityDataFlagTimerFlag3-64) | 1<<(entityDataFlagBodyRotationBlocked-64))
+	if hi := uint64(metadata[protocol.EntityDataKeyFlagsTwo].(int64)); hi != want {
+		t.Fatalf("downgraded flags = %x, want %x", hi, want)
+	}
+	if lo := metadata[protocol.EntityDataKeyFlags].(int64); lo != 1<<protocol.EntityDataFlagOnFire {
+		t.Fatalf("downgraded flags = %x, want %x", lo, 1<<protocol.EntityDataFlagOnFire)
+	}
+}
+
+// copyMetadata returns a shallow copy of the entity metadata passed.
+func copyMetadata(metadata map[uint32]any) map[uint32]any {
+	c := make(map[uint32]any, len(metadata))
+	for k, v := range metadata {
+		c[k] = v
+	}
+	return c
+}
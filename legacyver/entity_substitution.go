@@ -0,0 +1,105 @@
+package legacyver
+
+import (
+	"bytes"
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"maps"
+)
+
+// entitySubstitution describes an entity that was added in a version of the game, and the vanilla entity that
+// replaces it for older clients.
+type entitySubstitution struct {
+	// version is the protocol version in which the entity was added.
+	version int32
+	// identifier is the identifier of the entity, and replacement the identifier of the entity that replaces it.
+	identifier, replacement string
+}
+
+// entitySubstitutions holds the entities added since the oldest supported version, together with the visually
+// closest entities older clients know.
+var entitySubstitutions = []entitySubstitution{
+	{version: proto.ID766, identifier: "minecraft:creaking", replacement: "minecraft:zombie"},
+	{version: proto.ID685, identifier: "minecraft:bogged", replacement: "minecraft:skeleton"},
+	{version: proto.ID685, identifier: "minecraft:breeze", replacement: "minecraft:blaze"},
+	{version: proto.ID685, identifier: "minecraft:wind_charge_projectile", replacement: "minecraft:snowball"},
+	{version: proto.ID685, identifier: "minecraft:breeze_wind_charge_projectile", replacement: "minecraft:snowball"},
+}
+
+// SetEntitySubstitution makes entities with the identifier passed show up as the entity with the replacement
+// identifier for clients using the protocol. It may be used for entities that the client doesn't know, such as
+// custom entities without a resource pack. The identifier is also removed from the AvailableActorIdentifiers sent
+// to the client.
+func (p *Protocol) SetEntitySubstitution(identifier, replacement string) {
+	p.entitiesMu.Lock()
+	defer p.entitiesMu.Unlock()
+	entities := maps.Clone(p.entitySubstitutionsLocked())
+	entities[identifier] = replacement
+	p.entities = entities
+}
+
+// entitySubstitutions returns the entity substitutions of the protocol, indexed by the identifier of the entity
+// that is replaced. The map returned must not be modified.
+func (p *Protocol) entitySubstitutions() map[string]string {
+	p.entitiesMu.Lock()
+	defer p.entitiesMu.Unlock()
+	return p.entitySubstitutionsLocked()
+}
+
+// entitySubstitutionsLocked returns the entity substitutions of the protocol, filling them with the vanilla
+// substitutions for the version of the protocol if they weren't set yet. entitiesMu must be held.
+func (p *Protocol) entitySubstitutionsLocked() map[string]string {
+	if p.entities == nil {
+		p.entities = make(map[string]string)
+		for _, sub := range entitySubstitutions {
+			if sub.version > p.id {
+				p.entities[sub.identifier] = sub.replacement
+			}
+		}
+	}
+	return p.entities
+}
+
+// downgradeEntityType returns the entity identifier sent to the client in place of the identifier passed, and
+// whether it was substituted.
+func (p *Protocol) downgradeEntityType(identifier string) (string, bool) {
+	if replacement, ok := p.entitySubstitutions()[identifier]; ok {
+		return replacement, true
+	}
+	return identifier, false
+}
+
+// downgradeActorIdentifiers removes the entities that are substituted from the serialised entity identifiers of an
+// AvailableActorIdentifiers packet, so that the entity list of the client matches the entities it is sent.
+func (p *Protocol) downgradeActorIdentifiers(serialised []byte) []byte {
+	entities := p.entitySubstitutions()
+	if len(entities) == 0 {
+		return serialised
+	}
+	var identifiers map[string]any
+	if err := nbt.NewDecoderWithEncoding(bytes.NewBuffer(serialised), nbt.NetworkLittleEndian).Decode(&identifiers); err != nil {
+		return serialised
+	}
+	list, ok := identifiers["idlist"].([]any)
+	if !ok {
+		return serialised
+	}
+	filtered := make([]any, 0, len(list))
+	for _, entry := range list {
+		if m, ok := entry.(map[string]any); ok {
+			if id, _ := m["id"].(string); id != "" {
+				if _, substituted := entities[id]; substituted {
+					continue
+				}
+			}
+		}
+		filtered = append(filtered, entry)
+	}
+	identifiers["idlist"] = filtered
+
+	buf := bytes.NewBuffer(nil)
+	if err := nbt.NewEncoderWithEncoding(buf, nbt.NetworkLittleEndian).Encode(identifiers); err != nil {
+		return serialised
+	}
+	return buf.Bytes()
+}
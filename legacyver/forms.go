@@ -0,0 +1,132 @@
+package legacyver
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// formTranslation holds how the elements of a custom form were rewritten for a legacy client.
+type formTranslation struct {
+	// elements is the number of elements of the form sent by the server.
+	elements int
+	// indices holds the index of every element sent to the client in the form sent by the server.
+	indices []int
+}
+
+// downgradeForm rewrites the JSON of a form for a client of a supported version. 1.21.70 added headers and dividers
+// to custom forms, labels, headers and dividers to menu forms and tooltips to the elements of custom forms. It is
+// newer than every supported version, including the latest, so forms are rewritten for all of them. The translation
+// returned is nil if response indices don't need to be remapped. Forms that can't be parsed are returned as they
+// are.
+func downgradeForm(data []byte) ([]byte, *formTranslation) {
+	var form map[string]any
+	if err := json.Unmarshal(data, &form); err != nil {
+		return data, nil
+	}
+	var translation *formTranslation
+	switch form["type"] {
+	case "custom_form":
+		content, ok := form["content"].([]any)
+		if !ok {
+			return data, nil
+		}
+		form["content"], translation = downgradeCustomFormContent(content)
+	case "form":
+		elements, ok := form["elements"].([]any)
+		if !ok {
+			return data, nil
+		}
+		text, _ := form["content"].(string)
+		form["content"], form["buttons"] = downgradeMenuElements(text, elements)
+		delete(form, "elements")
+	default:
+		return data, nil
+	}
+	b, err := json.Marshal(form)
+	if err != nil {
+		return data, nil
+	}
+	return b, translation
+}
+
+// downgradeCustomFormContent rewrites the elements of a custom form for a legacy client. Headers become bold
+// labels, dividers are removed and tooltips are dropped. The translation returned is nil if no elements were
+// removed.
+func downgradeCustomFormContent(content []any) ([]any, *formTranslation) {
+	elements := make([]any, 0, len(content))
+	indices := make([]int, 0, len(content))
+	for i, e := range content {
+		element, ok := e.(map[string]any)
+		if !ok {
+			elements, indices = append(elements, e), append(indices, i)
+			continue
+		}
+		switch element["type"] {
+		case "divider":
+			continue
+		case "header":
+			text, _ := element["text"].(string)
+			element = map[string]any{"type": "label", "text": "§l" + text + "§r"}
+		default:
+			delete(element, "tooltip")
+		}
+		elements, indices = append(elements, element), append(indices, i)
+	}
+	if len(elements) == len(content) {
+		return elements, nil
+	}
+	return elements, &formTranslation{elements: len(content), indices: indices}
+}
+
+// downgradeMenuElements rewrites the elements of a menu form for a legacy client, which only knows buttons. The
+// text of headers and labels is added to the content of the form and dividers become empty lines. Buttons keep
+// their order, so the index of the button clicked doesn't need to be remapped.
+func downgradeMenuElements(content string, elements []any) (string, []any) {
+	lines := []string{content}
+	buttons := make([]any, 0, len(elements))
+	for _, e := range elements {
+		element, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		text, _ := element["text"].(string)
+		switch element["type"] {
+		case "header":
+			lines = append(lines, "§l"+text+"§r")
+		case "label":
+			lines = append(lines, text)
+		case "divider":
+			lines = append(lines, "")
+		default:
+			delete(element, "type")
+			delete(element, "tooltip")
+			buttons = append(buttons, element)
+		}
+	}
+	if content == "" {
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n"), buttons
+}
+
+// upgradeFormResponse remaps the response of a legacy client to a custom form to the elements of the form sent by
+// the server. Elements that weren't sent to the client respond with null, like labels do. Responses that can't be
+// parsed, such as the null response of a closed form, are returned as they are.
+func upgradeFormResponse(data []byte, translation *formTranslation) []byte {
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil || len(values) != len(translation.indices) {
+		return data
+	}
+	response := make([]json.RawMessage, translation.elements)
+	for i := range response {
+		response[i] = json.RawMessage("null")
+	}
+	for i, value := range values {
+		response[translation.indices[i]] = value
+	}
+	b, err := json.Marshal(response)
+	if err != nil {
+		return data
+	}
+	return b
+}
@@ -0,0 +1,100 @@
+package legacyver
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// decodeJSON decodes the JSON passed into generic values, so that forms can be compared regardless of key order.
+func decodeJSON(t *testing.T, data []byte) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	return v
+}
+
+func TestDowngradeCustomForm(t *testing.T) {
+	tests := []struct {
+		name, form, want string
+		// indices holds the indices of the elements sent to the client, or nil if the responses aren't remapped.
+		indices []int
+	}{
+		{
+			name:    "divider removed",
+			form:    `{"type":"custom_form","title":"t","content":[{"type":"label","text":"a"},{"type":"divider"},{"type":"input","text":"b"}]}`,
+			want:    `{"type":"custom_form","title":"t","content":[{"type":"label","text":"a"},{"type":"input","text":"b"}]}`,
+			indices: []int{0, 2},
+		},
+		{
+			name: "header and tooltip rewritten",
+			form: `{"type":"custom_form","title":"t","content":[{"type":"header","text":"h"},{"type":"toggle","text":"b","tooltip":"c"}]}`,
+			want: `{"type":"custom_form","title":"t","content":[{"type":"label","text":"§lh§r"},{"type":"toggle","text":"b"}]}`,
+		},
+		{
+			name: "unchanged",
+			form: `{"type":"custom_form","title":"t","content":[{"type":"slider","text":"a","min":0,"max":10}]}`,
+			want: `{"type":"custom_form","title":"t","content":[{"type":"slider","text":"a","min":0,"max":10}]}`,
+		},
+	}
+	for _, test := range tests {
+		data, translation := downgradeForm([]byte(test.form))
+		if got, want := decodeJSON(t, data), decodeJSON(t, []byte(test.want)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: form = %s, want %s", test.name, data, test.want)
+		}
+		if test.indices == nil {
+			if translation != nil {
+				t.Errorf("%v: got translation %+v, want none", test.name, translation)
+			}
+			continue
+		}
+		if translation == nil || !slices.Equal(translation.indices, test.indices) {
+			t.Errorf("%v: translation = %+v, want indices %v", test.name, translation, test.indices)
+		}
+	}
+}
+
+func TestDowngradeMenuForm(t *testing.T) {
+	tests := []struct {
+		name, form, want string
+	}{
+		{
+			name: "elements become content and buttons",
+			form: `{"type":"form","title":"t","content":"c","elements":[{"type":"header","text":"h"},{"type":"label","text":"l"},{"type":"divider"},{"type":"button","text":"b","tooltip":"x"}]}`,
+			want: `{"type":"form","title":"t","content":"c\n§lh§r\nl\n","buttons":[{"text":"b"}]}`,
+		},
+		{
+			name: "without content",
+			form: `{"type":"form","title":"t","content":"","elements":[{"type":"label","text":"l"},{"type":"button","text":"b","image":{"type":"path","data":"p"}}]}`,
+			want: `{"type":"form","title":"t","content":"l","buttons":[{"text":"b","image":{"type":"path","data":"p"}}]}`,
+		},
+	}
+	for _, test := range tests {
+		data, translation := downgradeForm([]byte(test.form))
+		if got, want := decodeJSON(t, data), decodeJSON(t, []byte(test.want)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: form = %s, want %s", test.name, data, test.want)
+		}
+		if translation != nil {
+			t.Errorf("%v: got translation %+v, want none, as buttons keep their order", test.name, translation)
+		}
+	}
+}
+
+func TestUpgradeFormResponse(t *testing.T) {
+	translation := &formTranslation{elements: 5, indices: []int{0, 2, 4}}
+	tests := []struct {
+		name, response, want string
+	}{
+		{name: "remapped", response: `[null,"text",true]`, want: `[null,null,"text",null,true]`},
+		{name: "closed form", response: `null`, want: `null`},
+		{name: "wrong element count", response: `["text",true]`, want: `["text",true]`},
+	}
+	for _, test := range tests {
+		if got := upgradeFormResponse([]byte(test.response), translation); string(got) != test.want {
+			t.Errorf("%v: response = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"sync"
+)
+
+// inputFlagSchema describes the changes made to the input flags of the PlayerAuthInput packet in a version of the
+// game.
+type inputFlagSchema struct {
+	// version is the protocol version in which the changes were made.
+	version int32
+	// addedFlags holds the input flags of the latest version that were added in the version. For older clients
+	// these flags are removed and the flags after them are moved down, wherever in the enum they were inserted.
+	addedFlags []int
+	// upgrade synthesises the added flags from the other flags of input, which holds the input of an older client
+	// in the layout of the latest version. previous holds the previous upgraded input of the client, and may be
+	// empty. upgrade may be nil if the added flags can't be synthesised, in which case they are left unset.
+	upgrade func(input, previous protocol.Bitset)
+}
+
+// inputFlagSchemas holds all changes made to the input flags since the oldest supported version, ordered from oldest
+// to newest.
+var inputFlagSchemas = []inputFlagSchema{
+	{
+		version:    proto.ID685,
+		addedFlags: []int{packet.InputFlagStartUsingItem},
+	},
+	{
+		version: proto.ID712,
+		addedFlags: []int{
+			packet.InputFlagCameraRelativeMovementEnabled,
+			packet.InputFlagRotControlledByMoveDirection,
+			packet.InputFlagStartSpinAttack,
+			packet.InputFlagStopSpinAttack,
+		},
+	},
+	{
+		// 1.21.50 added the raw state of the jump and sneak buttons, which older clients only send as whether the
+		// button is held down.
+		version: proto.ID766,
+		addedFlags: []int{
+			packet.InputFlagIsHotbarTouchOnly,
+			packet.InputFlagJumpReleasedRaw,
+			packet.InputFlagJumpPressedRaw,
+			packet.InputFlagJumpCurrentRaw,
+			packet.InputFlagSneakReleasedRaw,
+			packet.InputFlagSneakPressedRaw,
+			packet.InputFlagSneakCurrentRaw,
+		},
+		upgrade: func(input, previous protocol.Bitset) {
+			synthesiseRawInput(input, previous, packet.InputFlagJumpDown, packet.InputFlagJumpCurrentRaw, packet.InputFlagJumpPressedRaw, packet.InputFlagJumpReleasedRaw)
+			synthesiseRawInput(input, previous, packet.InputFlagSneakDown, packet.InputFlagSneakCurrentRaw, packet.InputFlagSneakPressedRaw, packet.InputFlagSneakReleasedRaw)
+		},
+	},
+}
+
+// inputFlagTables holds the input flag index table of every protocol version it was built for, indexed by the
+// protocol version.
+var inputFlagTables sync.Map
+
+// inputFlagIndices returns the input flag index table of the protocol version passed. The table holds the index of
+// every flag of the version in the latest version, indexed by its index in the version.
+func inputFlagIndices(id int32) []int {
+	if table, ok := inputFlagTables.Load(id); ok {
+		return table.([]int)
+	}
+	added := make(map[int]struct{})
+	for _, schema := range inputFlagSchemas {
+		if schema.version <= id {
+			continue
+		}
+		for _, flag := range schema.addedFlags {
+			added[flag] = struct{}{}
+		}
+	}
+	table := make([]int, 0, packet.PlayerAuthInputBitsetSize)
+	for flag := 0; flag < packet.PlayerAuthInputBitsetSize; flag++ {
+		if _, ok := added[flag]; !ok {
+			table = append(table, flag)
+		}
+	}
+	inputFlagTables.Store(id, table)
+	return table
+}
+
+// inputBitsetSize returns the size of the input flag bitset sent by clients of the protocol version passed.
+func inputBitsetSize(id int32) int {
+	if id >= proto.ID766 {
+		return packet.PlayerAuthInputBitsetSize
+	}
+	return 64
+}
+
+// downgradeInputData downgrades the input flags of the latest version to the protocol version passed. Flags that
+// don't exist in the version are dropped.
+func downgradeInputData(id int32, input protocol.Bitset) protocol.Bitset {
+	if id >= proto.ID766 && input.Len() == packet.PlayerAuthInputBitsetSize {
+		return input
+	}
+	size := inputBitsetSize(id)
+	legacy := protocol.NewBitset(size)
+	for index, flag := range inputFlagIndices(id) {
+		if index < size && flag < input.Len() && input.Load(flag) {
+			legacy.Set(index)
+		}
+	}
+	return legacy
+}
+
+// upgradeInputData upgrades the input flags of the protocol version passed to the latest version. Flags that don't
+// exist in the version are synthesised from the other flags and the previous upgraded input of the client, which
+// may be empty, where possible.
+func upgradeInputData(id int32, input, previous protocol.Bitset) protocol.Bitset {
+	if id >= proto.ID766 && input.Len() == packet.PlayerAuthInputBitsetSize {
+		return input
+	}
+	latest := protocol.NewBitset(packet.PlayerAuthInputBitsetSize)
+	for index, flag := range inputFlagIndices(id) {
+		if index < input.Len() && input.Load(index) {
+			latest.Set(flag)
+		}
+	}
+	for _, schema := range inputFlagSchemas {
+		if schema.version > id && schema.upgrade != nil {
+			schema.upgrade(latest, previous)
+		}
+	}
+	return latest
+}
+
+// synthesiseRawInput sets the raw flags of a button from the flag that holds whether the button is held down, using
+// the previous input to find out if the button was just pressed or released.
+func synthesiseRawInput(input, previous protocol.Bitset, down, current, pressed, released int) {
+	wasDown := previous.Len() > down && previous.Load(down)
+	if input.Load(down) {
+		input.Set(current)
+		if !wasDown {
+			input.Set(pressed)
+		}
+	} else if wasDown {
+		input.Set(released)
+	}
+}
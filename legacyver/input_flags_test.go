@@ -0,0 +1,125 @@
+package legacyver
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// inputBitset returns a bitset of the size passed with the flags passed set.
+func inputBitset(size int, flags ...int) protocol.Bitset {
+	b := protocol.NewBitset(size)
+	for _, flag := range flags {
+		b.Set(flag)
+	}
+	return b
+}
+
+// setFlags returns the flags set in the bitset passed, in ascending order.
+func setFlags(b protocol.Bitset) []int {
+	var flags []int
+	for i := 0; i < b.Len(); i++ {
+		if b.Load(i) {
+			flags = append(flags, i)
+		}
+	}
+	return flags
+}
+
+func TestInputFlagIndices(t *testing.T) {
+	tests := []struct {
+		id int32
+		// last is the last flag of the version in the latest version.
+		last int
+	}{
+		{id: proto.ID671, last: packet.InputFlagDownRight},
+		{id: proto.ID685, last: packet.InputFlagStartUsingItem},
+		{id: proto.ID686, last: packet.InputFlagStartUsingItem},
+		{id: proto.ID712, last: packet.InputFlagStopSpinAttack},
+		{id: proto.ID748, last: packet.InputFlagStopSpinAttack},
+		{id: proto.ID766, last: packet.InputFlagSneakCurrentRaw},
+	}
+	for _, test := range tests {
+		table := inputFlagIndices(test.id)
+		if len(table) != test.last+1 {
+			t.Errorf("%v: got %v flags, want %v", test.id, len(table), test.last+1)
+			continue
+		}
+		// No flags were inserted in the middle of the enum so far, so every flag keeps its index.
+		for index, flag := range table {
+			if index != flag {
+				t.Errorf("%v: flag %v is mapped to %v", test.id, index, flag)
+				break
+			}
+		}
+	}
+}
+
+func TestInputFlagRoundTrip(t *testing.T) {
+	latest := inputBitset(packet.PlayerAuthInputBitsetSize,
+		packet.InputFlagSprintDown, packet.InputFlagDownRight, packet.InputFlagStartUsingItem,
+		packet.InputFlagStartSpinAttack, packet.InputFlagIsHotbarTouchOnly,
+	)
+	tests := []struct {
+		id   int32
+		want []int
+	}{
+		{id: proto.ID671, want: []int{packet.InputFlagSprintDown, packet.InputFlagDownRight}},
+		{id: proto.ID686, want: []int{packet.InputFlagSprintDown, packet.InputFlagDownRight, packet.InputFlagStartUsingItem}},
+		{id: proto.ID748, want: []int{packet.InputFlagSprintDown, packet.InputFlagDownRight, packet.InputFlagStartUsingItem, packet.InputFlagStartSpinAttack}},
+	}
+	for _, test := range tests {
+		legacy := downgradeInputData(test.id, latest)
+		if legacy.Len() != 64 {
+			t.Errorf("%v: downgraded bitset has %v flags, want 64", test.id, legacy.Len())
+		}
+		upgraded := upgradeInputData(test.id, legacy, protocol.Bitset{})
+		if got := setFlags(upgraded); !slices.Equal(got, test.want) {
+			t.Errorf("%v: round trip flags = %v, want %v", test.id, got, test.want)
+		}
+	}
+}
+
+func TestSynthesisedRawInput(t *testing.T) {
+	tests := []struct {
+		name string
+		// down and wasDown are whether the button is held in the input and previous input.
+		down, wasDown bool
+		want          []int
+	}{
+		{name: "pressed", down: true, want: []int{packet.InputFlagJumpPressedRaw, packet.InputFlagJumpCurrentRaw}},
+		{name: "held", down: true, wasDown: true, want: []int{packet.InputFlagJumpCurrentRaw}},
+		{name: "released", wasDown: true, want: []int{packet.InputFlagJumpReleasedRaw}},
+		{name: "idle"},
+	}
+	for _, test := range tests {
+		for _, button := range []struct {
+			down, offset int
+		}{
+			{down: packet.InputFlagJumpDown},
+			{down: packet.InputFlagSneakDown, offset: packet.InputFlagSneakReleasedRaw - packet.InputFlagJumpReleasedRaw},
+		} {
+			legacy, previous := protocol.NewBitset(64), protocol.NewBitset(packet.PlayerAuthInputBitsetSize)
+			var want []int
+			if test.down {
+				legacy.Set(button.down)
+				want = append(want, button.down)
+			}
+			if test.wasDown {
+				previous.Set(button.down)
+			}
+			for _, flag := range test.want {
+				want = append(want, flag+button.offset)
+			}
+
+			slices.Sort(want)
+			upgraded := upgradeInputData(proto.ID748, legacy, previous)
+			if got := setFlags(upgraded); !slices.Equal(got, want) {
+				t.Errorf("%v (flag %v): upgraded flags = %v, want %v", test.name, button.down, got, want)
+			}
+		}
+	}
+}
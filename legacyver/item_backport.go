@@ -0,0 +1,239 @@
+package legacyver
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/akmalfairuz/legacy-version/packbuilder"
+	"github.com/df-mc/dragonfly/server/item"
+	"github.com/df-mc/dragonfly/server/item/category"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/resource"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// backportedItemNamespace is the namespace of the custom items that replace items missing from a legacy version.
+const backportedItemNamespace = "legacyver"
+
+// backportedItem is a custom item that replaces an item of the latest version for clients of a legacy version
+// that don't know it.
+type backportedItem struct {
+	// identifier is the identifier of the custom item, and name its display name.
+	identifier, name string
+	texture          image.Image
+	maxCount         int
+}
+
+func (i backportedItem) EncodeItem() (string, int16) {
+	return i.identifier, 0
+}
+
+func (i backportedItem) Name() string {
+	return i.name
+}
+
+func (i backportedItem) Texture() image.Image {
+	return i.texture
+}
+
+func (i backportedItem) Category() category.Category {
+	return category.Items()
+}
+
+func (i backportedItem) MaxCount() int {
+	return i.maxCount
+}
+
+// newBackportedItem creates the custom item that replaces the latest item with the name passed, using the texture
+// passed. The maximum stack size is taken from dragonfly if it implements the item, and is 64 otherwise.
+func newBackportedItem(name string, texture image.Image) backportedItem {
+	_, short, _ := strings.Cut(name, ":")
+	maxCount := 64
+	if it, ok := world.ItemByName(name, 0); ok {
+		if counter, ok := it.(item.MaxCounter); ok {
+			maxCount = counter.MaxCount()
+		}
+	}
+	return backportedItem{
+		identifier: backportedItemNamespace + ":" + short,
+		name:       itemDisplayName(name),
+		texture:    texture,
+		maxCount:   maxCount,
+	}
+}
+
+// MissingItems returns the names of the items of the latest version that don't exist in the legacy version and
+// aren't replaced by a custom item.
+func (t *DefaultItemTranslator) MissingItems() []string {
+	if t.latest == t.mapping {
+		return nil
+	}
+	var names []string
+	for _, rid := range t.latest.RuntimeIDs() {
+		name, ok := t.latest.ItemRuntimeIDToName(rid)
+		if !ok {
+			continue
+		}
+		if _, ok := t.originalToCustom[rid]; ok {
+			continue
+		}
+		if _, ok := t.legacyItemType(name, 0); !ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// BackportItems registers every item returned by MissingItems for which texture returns an image as a custom
+// item, so that clients see the item itself instead of its substitute. The custom items registered are returned.
+// BackportItems must be called before any connection uses the translator.
+func (t *DefaultItemTranslator) BackportItems(texture func(name string) (image.Image, bool)) []world.CustomItem {
+	var items []world.CustomItem
+	for _, name := range t.MissingItems() {
+		img, ok := texture(name)
+		if !ok {
+			continue
+		}
+		it := newBackportedItem(name, img)
+		t.Register(it, name)
+		items = append(items, it)
+	}
+	t.backported = append(t.backported, items...)
+	return items
+}
+
+// BackportedItems returns the custom items registered through BackportItems.
+func (t *DefaultItemTranslator) BackportedItems() []world.CustomItem {
+	return t.backported
+}
+
+// BackportItems registers the items of the latest version that are missing from the version of the protocol as
+// custom items, using the item textures of the vanilla resource pack extracted to the directory passed. Items
+// without an item texture, such as most block items, keep being substituted. BackportItems must be called
+// before the protocol is used, and the pack returned by BackportResourcePack must be added to the resource packs
+// of the listener.
+func (p *Protocol) BackportItems(vanillaPackDir string) error {
+	t, ok := p.itemTranslator.(*DefaultItemTranslator)
+	if !ok {
+		return fmt.Errorf("backport items: item translator %T does not support backporting", p.itemTranslator)
+	}
+	textures, err := readItemTextureAtlas(vanillaPackDir)
+	if err != nil {
+		return fmt.Errorf("backport items: %w", err)
+	}
+	t.BackportItems(func(name string) (image.Image, bool) {
+		_, short, _ := strings.Cut(name, ":")
+		path, ok := textures[short]
+		if !ok {
+			return nil, false
+		}
+		img, err := readPNG(filepath.Join(vanillaPackDir, path+".png"))
+		return img, err == nil
+	})
+	return nil
+}
+
+// backportPacks holds the UUIDs of the resource packs built by BackportResourcePack, with the ID of the protocol
+// each pack was built for.
+var backportPacks sync.Map
+
+// BackportResourcePack builds the resource pack holding the textures of the items backported through
+// BackportItems. False is returned if no items were backported. The pack should be added to the resource packs
+// of the listener. It is only sent to clients using this protocol: the protocols of this package remove the
+// backport packs of other versions from the packs sent to their clients.
+func (p *Protocol) BackportResourcePack() (*resource.Pack, bool) {
+	t, ok := p.itemTranslator.(*DefaultItemTranslator)
+	if !ok || len(t.BackportedItems()) == 0 {
+		return nil, false
+	}
+	pack, ok := packbuilder.BuildResourcePack(t.BackportedItems(), p.ver)
+	if ok {
+		backportPacks.Store(pack.UUID().String(), p.id)
+	}
+	return pack, ok
+}
+
+// isForeignBackportPack checks if the resource pack with the UUID passed is a backport pack built for another
+// protocol.
+func (p *Protocol) isForeignBackportPack(uuid string) bool {
+	id, ok := backportPacks.Load(uuid)
+	return ok && id.(int32) != p.id
+}
+
+// downgradeTexturePacks removes the backport packs of other protocols from the texture packs passed.
+func (p *Protocol) downgradeTexturePacks(packs []protocol.TexturePackInfo) []protocol.TexturePackInfo {
+	filtered := make([]protocol.TexturePackInfo, 0, len(packs))
+	for _, pack := range packs {
+		if !p.isForeignBackportPack(pack.UUID.String()) {
+			filtered = append(filtered, pack)
+		}
+	}
+	return filtered
+}
+
+// downgradeStackPacks removes the backport packs of other protocols from the resource pack stack passed.
+func (p *Protocol) downgradeStackPacks(packs []protocol.StackResourcePack) []protocol.StackResourcePack {
+	filtered := make([]protocol.StackResourcePack, 0, len(packs))
+	for _, pack := range packs {
+		if !p.isForeignBackportPack(pack.UUID) {
+			filtered = append(filtered, pack)
+		}
+	}
+	return filtered
+}
+
+// readItemTextureAtlas reads textures/item_texture.json of the resource pack in the directory passed and returns
+// the path of the first texture of every entry, without extension, indexed by the name of the entry.
+func readItemTextureAtlas(dir string) (map[string]string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "textures", "item_texture.json"))
+	if err != nil {
+		return nil, err
+	}
+	var atlas struct {
+		TextureData map[string]struct {
+			Textures any `json:"textures"`
+		} `json:"texture_data"`
+	}
+	if err := json.Unmarshal(b, &atlas); err != nil {
+		return nil, fmt.Errorf("decode item texture atlas: %w", err)
+	}
+	textures := make(map[string]string, len(atlas.TextureData))
+	for name, data := range atlas.TextureData {
+		if path, ok := firstTexturePath(data.Textures); ok {
+			textures[name] = path
+		}
+	}
+	return textures, nil
+}
+
+// firstTexturePath returns the first texture path of a texture atlas entry, which may be a path, an object with a
+// path or a list of either.
+func firstTexturePath(textures any) (string, bool) {
+	switch textures := textures.(type) {
+	case string:
+		return textures, true
+	case map[string]any:
+		path, ok := textures["path"].(string)
+		return path, ok
+	case []any:
+		if len(textures) > 0 {
+			return firstTexturePath(textures[0])
+		}
+	}
+	return "", false
+}
+
+// readPNG reads the PNG image at the path passed.
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
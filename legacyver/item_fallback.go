@@ -0,0 +1,100 @@
+package legacyver
+
+import (
+	"encoding/json"
+	"maps"
+	"strings"
+)
+
+// ItemSubstitution describes the item that replaces an item of the latest version that doesn't exist in a legacy
+// version.
+type ItemSubstitution struct {
+	// Name is the name of the replacement item, as named in the latest version. If empty, or if the item doesn't
+	// exist in the legacy version either, the placeholder item is used.
+	Name string `json:"name,omitempty"`
+	// Metadata is the metadata value of the replacement item.
+	Metadata uint32 `json:"metadata,omitempty"`
+}
+
+// itemPlaceholder is the item sent for items that don't exist in a legacy version and have no substitute.
+const itemPlaceholder = "minecraft:info_update"
+
+// originalItemKey is the key of the NBT tag added to substituted items, which holds the item that was replaced so
+// that the substitution can be reversed when the client sends the item back.
+const originalItemKey = "legacy_version:original_item"
+
+// parseItemSubstitutions parses an item substitution table, indexed by the name of the item that is replaced.
+func parseItemSubstitutions(raw []byte) map[string]ItemSubstitution {
+	var substitutions map[string]ItemSubstitution
+	if err := json.Unmarshal(raw, &substitutions); err != nil {
+		panic(err)
+	}
+	return substitutions
+}
+
+// annotateSubstitute returns a copy of the NBT of a substituted item with a custom name and lore naming the original
+// item, and a tag holding the original item and the display tag it replaced.
+func annotateSubstitute(data map[string]any, name string, metadata uint32) map[string]any {
+	original := map[string]any{"Name": name, "Damage": int16(metadata)}
+
+	data = maps.Clone(data)
+	if data == nil {
+		data = make(map[string]any)
+	}
+	display, _ := data["display"].(map[string]any)
+	if display != nil {
+		original["display"] = display
+	}
+	display = maps.Clone(display)
+	if display == nil {
+		display = make(map[string]any)
+	}
+	if _, ok := display["Name"]; !ok {
+		display["Name"] = "§r" + itemDisplayName(name)
+	}
+	lore, _ := display["Lore"].([]any)
+	display["Lore"] = append(lore[:len(lore):len(lore)], "§r§8"+name)
+
+	data["display"] = display
+	data[originalItemKey] = original
+	return data
+}
+
+// restoreSubstitute reverses annotateSubstitute. It returns the name and metadata of the original item and the NBT
+// the item had before it was substituted. False is returned if the NBT isn't that of a substituted item.
+func restoreSubstitute(data map[string]any) (name string, metadata uint32, nbt map[string]any, ok bool) {
+	original, ok := data[originalItemKey].(map[string]any)
+	if !ok {
+		return "", 0, data, false
+	}
+	name, _ = original["Name"].(string)
+	damage, _ := original["Damage"].(int16)
+
+	nbt = maps.Clone(data)
+	delete(nbt, originalItemKey)
+	if display, ok := original["display"]; ok {
+		nbt["display"] = display
+	} else {
+		delete(nbt, "display")
+	}
+	if len(nbt) == 0 {
+		nbt = nil
+	}
+	return name, uint32(damage), nbt, name != ""
+}
+
+// itemDisplayName returns a readable name for the item identifier passed, such as "Pale Oak Planks" for
+// "minecraft:pale_oak_planks".
+func itemDisplayName(identifier string) string {
+	_, name, ok := strings.Cut(identifier, ":")
+	if !ok {
+		name = identifier
+	}
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,157 @@
+package legacyver
+
+import (
+	"github.com/df-mc/worldupgrader/blockupgrader"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"maps"
+)
+
+// translateNBT returns a deep copy of the NBT value passed, in which every item compound, such as the contents of a
+// shulker box or bundle or the projectile of a crossbow, is passed to item and every block state compound is
+// passed to block. Nested values are translated before the compounds holding them.
+func translateNBT(v any, item, block func(m map[string]any)) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = translateNBT(e, item, block)
+		}
+		if isItemCompound(m) {
+			item(m)
+		} else if isBlockCompound(m) {
+			block(m)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = translateNBT(e, item, block)
+		}
+		return s
+	case []map[string]any:
+		s := make([]map[string]any, len(v))
+		for i, e := range v {
+			s[i] = translateNBT(e, item, block).(map[string]any)
+		}
+		return s
+	}
+	return v
+}
+
+// isItemCompound checks if the NBT compound passed holds an item, which has a name and a count.
+func isItemCompound(m map[string]any) bool {
+	_, hasName := m["Name"].(string)
+	_, hasCount := m["Count"].(uint8)
+	return hasName && hasCount
+}
+
+// isBlockCompound checks if the NBT compound passed holds a block state, which has a name and states.
+func isBlockCompound(m map[string]any) bool {
+	_, hasName := m["name"].(string)
+	_, hasStates := m["states"].(map[string]any)
+	return hasName && hasStates
+}
+
+// downgradeNBT returns a copy of the item NBT passed with the items and block states it holds downgraded.
+func (t *DefaultItemTranslator) downgradeNBT(data map[string]any) map[string]any {
+	if data == nil {
+		return nil
+	}
+	return translateNBT(data, t.downgradeItemCompound, t.downgradeBlockCompound).(map[string]any)
+}
+
+// upgradeNBT returns a copy of the item NBT passed with the items and block states it holds upgraded.
+func (t *DefaultItemTranslator) upgradeNBT(data map[string]any) map[string]any {
+	if data == nil {
+		return nil
+	}
+	return translateNBT(data, t.upgradeItemCompound, t.upgradeBlockCompound).(map[string]any)
+}
+
+// downgradeItemCompound downgrades the name and metadata of an item compound. Items that don't exist in the legacy
+// version are substituted the same way as item stacks are.
+func (t *DefaultItemTranslator) downgradeItemCompound(m map[string]any) {
+	name, _ := m["Name"].(string)
+	rid, ok := t.latest.ItemNameToRuntimeID(name)
+	if !ok {
+		return
+	}
+	damage, _ := m["Damage"].(int16)
+	itemType, substituted := t.downgradeItemType(protocol.ItemType{NetworkID: rid, MetadataValue: uint32(damage)})
+	legacyName, ok := t.mapping.ItemRuntimeIDToName(itemType.NetworkID)
+	if !ok {
+		return
+	}
+	m["Name"], m["Damage"] = legacyName, int16(itemType.MetadataValue)
+	if substituted {
+		tag, _ := m["tag"].(map[string]any)
+		m["tag"] = annotateSubstitute(tag, name, uint32(damage))
+	}
+}
+
+// upgradeItemCompound upgrades the name and metadata of an item compound, restoring substituted items.
+func (t *DefaultItemTranslator) upgradeItemCompound(m map[string]any) {
+	name, _ := m["Name"].(string)
+	rid, ok := t.mapping.ItemNameToRuntimeID(name)
+	if !ok {
+		return
+	}
+	damage, _ := m["Damage"].(int16)
+	legacy := protocol.ItemType{NetworkID: rid, MetadataValue: uint32(damage)}
+
+	tag, _ := m["tag"].(map[string]any)
+	if itemType, nbt, ok := t.upgradeSubstitute(legacy, tag); ok {
+		latestName, _ := t.latest.ItemRuntimeIDToName(itemType.NetworkID)
+		m["Name"], m["Damage"] = latestName, int16(itemType.MetadataValue)
+		if nbt == nil {
+			delete(m, "tag")
+		} else {
+			m["tag"] = nbt
+		}
+		return
+	}
+	itemType := t.UpgradeItemType(legacy)
+	if latestName, ok := t.latest.ItemRuntimeIDToName(itemType.NetworkID); ok {
+		m["Name"], m["Damage"] = latestName, int16(itemType.MetadataValue)
+	}
+}
+
+// downgradeBlockCompound replaces a block state compound with the equivalent state of the legacy version. States
+// that don't exist in the legacy version are replaced by the same fallback as placed blocks, if the translator has a
+// block translator, and are left as they are otherwise.
+func (t *DefaultItemTranslator) downgradeBlockCompound(m map[string]any) {
+	state := blockStateFromCompound(m)
+	rid, ok := t.blockMapping.StateToRuntimeID(state)
+	if !ok {
+		latestRID, found := t.blockMappingLatest.StateToRuntimeID(state)
+		if !found || t.blocks == nil {
+			return
+		}
+		rid = t.blocks.DowngradeBlockRuntimeID(latestRID)
+	}
+	if state, ok := t.blockMapping.RuntimeIDToState(rid); ok {
+		setBlockCompound(m, state)
+	}
+}
+
+// upgradeBlockCompound replaces a block state compound with the equivalent state of the latest version.
+func (t *DefaultItemTranslator) upgradeBlockCompound(m map[string]any) {
+	setBlockCompound(m, blockupgrader.Upgrade(blockStateFromCompound(m)))
+}
+
+// blockStateFromCompound returns the block state held by a block state compound.
+func blockStateFromCompound(m map[string]any) blockupgrader.BlockState {
+	name, _ := m["name"].(string)
+	states, _ := m["states"].(map[string]any)
+	version, _ := m["version"].(int32)
+	return blockupgrader.BlockState{Name: name, Properties: states, Version: version}
+}
+
+// setBlockCompound sets the block state held by a block state compound.
+func setBlockCompound(m map[string]any, state blockupgrader.BlockState) {
+	states := maps.Clone(state.Properties)
+	if states == nil {
+		states = make(map[string]any)
+	}
+	m["name"], m["states"], m["version"] = state.Name, states, state.Version
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"maps"
+	"sync"
 )
 
 type ItemTranslator interface {
@@ -49,6 +51,21 @@ type DefaultItemTranslator struct {
 	ridToCustomItem    map[int32]world.CustomItem
 	originalToCustom   map[int32]int32
 	customToOriginal   map[int32]int32
+	// backported holds the custom items registered through BackportItems.
+	backported []world.CustomItem
+
+	substitutionsMu sync.RWMutex
+	substitutions   map[string]ItemSubstitution
+
+	// blocks translates the block runtime IDs of the block states held by item NBT, so that states missing from the
+	// legacy version are replaced like those of placed blocks are. It is nil if no block translator was set.
+	blocks blockRuntimeIDTranslator
+}
+
+// blockRuntimeIDTranslator is implemented by block translators that translate single block runtime IDs, such as
+// DefaultBlockTranslator.
+type blockRuntimeIDTranslator interface {
+	DowngradeBlockRuntimeID(input uint32) uint32
 }
 
 func NewItemTranslator(mapping mapping.Item, latestMapping mapping.Item, blockMapping mapping.Block, blockMappingLatest mapping.Block) *DefaultItemTranslator {
@@ -60,45 +77,107 @@ func (t *DefaultItemTranslator) DowngradeItemType(input protocol.ItemType) proto
 	if t.latest == t.mapping {
 		return input
 	}
+	itemType, _ := t.downgradeItemType(input)
+	return itemType
+}
+
+// downgradeItemType downgrades the input item type to a legacy item type. It also returns whether the item doesn't
+// exist in the legacy version, in which case it was replaced by its substitute or the placeholder item.
+func (t *DefaultItemTranslator) downgradeItemType(input protocol.ItemType) (protocol.ItemType, bool) {
 	if input.NetworkID == t.latest.Air() || input.NetworkID == 0 {
 		return protocol.ItemType{
 			NetworkID: t.mapping.Air(),
-		}
+		}, false
+	}
+	if networkID, ok := t.originalToCustom[input.NetworkID]; ok {
+		return protocol.ItemType{
+			NetworkID:     networkID,
+			MetadataValue: input.MetadataValue,
+		}, false
 	}
-	networkID := input.NetworkID
-	metadata := input.MetadataValue
 
-	var ok bool
-	if networkID, ok = t.originalToCustom[input.NetworkID]; !ok {
-		name, _ := t.latest.ItemRuntimeIDToName(input.NetworkID)
-		i := item.Downgrade(item.Item{
-			Name:     name,
-			Metadata: input.MetadataValue,
-			Version:  t.latest.ItemVersion(),
-		}, t.mapping.ItemVersion())
-		metadata = i.Metadata
-
-		if i.Name == "minecraft:netherstar" {
-			i.Name = "minecraft:nether_star"
+	name, _ := t.latest.ItemRuntimeIDToName(input.NetworkID)
+	if itemType, ok := t.legacyItemType(name, input.MetadataValue); ok {
+		return itemType, false
+	}
+	if sub, ok := t.itemSubstitution(name); ok && sub.Name != "" {
+		if itemType, ok := t.legacyItemType(sub.Name, sub.Metadata); ok {
+			return itemType, true
 		}
+	}
+	networkID, _ := t.mapping.ItemNameToRuntimeID(itemPlaceholder)
+	return protocol.ItemType{NetworkID: networkID}, true
+}
 
-		networkID, ok = t.mapping.ItemNameToRuntimeID(i.Name)
-		if !ok {
-			networkID, _ = t.mapping.ItemNameToRuntimeID("minecraft:info_update")
-		}
+// legacyItemType returns the legacy item type of the latest item with the name and metadata passed. False is
+// returned if the item doesn't exist in the legacy version.
+func (t *DefaultItemTranslator) legacyItemType(name string, metadata uint32) (protocol.ItemType, bool) {
+	i := item.Downgrade(item.Item{
+		Name:     name,
+		Metadata: metadata,
+		Version:  t.latest.ItemVersion(),
+	}, t.mapping.ItemVersion())
+	if networkID, ok := t.mapping.ItemNameToRuntimeID(i.Name); ok {
+		return protocol.ItemType{NetworkID: networkID, MetadataValue: i.Metadata}, true
+	}
+	// Downgrading may undo renames that happened before the legacy version, such as that of nether stars, in which
+	// case the latest name is still known.
+	if networkID, ok := t.mapping.ItemNameToRuntimeID(name); ok {
+		return protocol.ItemType{NetworkID: networkID, MetadataValue: metadata}, true
 	}
+	return protocol.ItemType{}, false
+}
+
+// WithItemSubstitutions sets the item substitution table used for latest items that don't exist in the legacy
+// version and returns the translator.
+func (t *DefaultItemTranslator) WithItemSubstitutions(substitutions map[string]ItemSubstitution) *DefaultItemTranslator {
+	t.substitutionsMu.Lock()
+	defer t.substitutionsMu.Unlock()
+	t.substitutions = substitutions
+	return t
+}
 
-	return protocol.ItemType{
-		NetworkID:     networkID,
-		MetadataValue: metadata,
+// WithBlockTranslator sets the block translator used to translate the block states held by item NBT, such as
+// those of the blocks an item can be placed on, and returns the translator. Block translators that can't translate
+// single runtime IDs are ignored.
+func (t *DefaultItemTranslator) WithBlockTranslator(blocks BlockTranslator) *DefaultItemTranslator {
+	t.blocks, _ = blocks.(blockRuntimeIDTranslator)
+	return t
+}
+
+// SetItemSubstitution overrides the item that replaces the latest item with the name passed if it doesn't exist in
+// the legacy version.
+func (t *DefaultItemTranslator) SetItemSubstitution(name string, substitution ItemSubstitution) {
+	t.substitutionsMu.Lock()
+	defer t.substitutionsMu.Unlock()
+	substitutions := maps.Clone(t.substitutions)
+	if substitutions == nil {
+		substitutions = make(map[string]ItemSubstitution)
 	}
+	substitutions[name] = substitution
+	t.substitutions = substitutions
+}
+
+// itemSubstitution returns the substitution of the latest item with the name passed, if it has one.
+func (t *DefaultItemTranslator) itemSubstitution(name string) (ItemSubstitution, bool) {
+	t.substitutionsMu.RLock()
+	defer t.substitutionsMu.RUnlock()
+	sub, ok := t.substitutions[name]
+	return sub, ok
 }
 
 func (t *DefaultItemTranslator) DowngradeItemStack(input protocol.ItemStack) protocol.ItemStack {
 	if t.latest == t.mapping {
 		return input
 	}
-	input.ItemType = t.DowngradeItemType(input.ItemType)
+	original := input.ItemType
+	itemType, substituted := t.downgradeItemType(input.ItemType)
+	input.ItemType = itemType
+	input.NBTData = t.downgradeNBT(input.NBTData)
+	if substituted {
+		name, _ := t.latest.ItemRuntimeIDToName(original.NetworkID)
+		input.NBTData = annotateSubstitute(input.NBTData, name, original.MetadataValue)
+	}
 
 	blockRuntimeId := uint32(0)
 	if input.NetworkID != t.mapping.Air() {
@@ -189,34 +268,49 @@ func (t *DefaultItemTranslator) UpgradeItemType(input protocol.ItemType) protoco
 			NetworkID: t.latest.Air(),
 		}
 	}
-	networkID := input.NetworkID
-	metadata := input.MetadataValue
-
-	var ok bool
-	if networkID, ok = t.customToOriginal[input.NetworkID]; !ok {
-		name, _ := t.mapping.ItemRuntimeIDToName(input.NetworkID)
-		i := item.Upgrade(item.Item{
-			Name:     name,
-			Metadata: input.MetadataValue,
-			Version:  t.mapping.ItemVersion(),
-		}, t.latest.ItemVersion())
-		networkID, ok = t.latest.ItemNameToRuntimeID(i.Name)
-		if !ok {
-			networkID, _ = t.latest.ItemNameToRuntimeID("minecraft:info_update")
+	if networkID, ok := t.customToOriginal[input.NetworkID]; ok {
+		return protocol.ItemType{
+			NetworkID:     networkID,
+			MetadataValue: input.MetadataValue,
 		}
 	}
+	name, _ := t.mapping.ItemRuntimeIDToName(input.NetworkID)
+	if itemType, ok := t.latestItemType(name, input.MetadataValue); ok {
+		return itemType
+	}
+	networkID, _ := t.latest.ItemNameToRuntimeID(itemPlaceholder)
+	return protocol.ItemType{NetworkID: networkID}
+}
 
-	return protocol.ItemType{
-		NetworkID:     networkID,
-		MetadataValue: metadata,
+// latestItemType returns the latest item type of the legacy item with the name and metadata passed, reversing
+// legacyItemType. False is returned if the item doesn't exist in the latest version.
+func (t *DefaultItemTranslator) latestItemType(name string, metadata uint32) (protocol.ItemType, bool) {
+	i := item.Upgrade(item.Item{
+		Name:     name,
+		Metadata: metadata,
+		Version:  t.mapping.ItemVersion(),
+	}, t.latest.ItemVersion())
+	if networkID, ok := t.latest.ItemNameToRuntimeID(i.Name); ok {
+		return protocol.ItemType{NetworkID: networkID, MetadataValue: i.Metadata}, true
+	}
+	// Items that legacyItemType sent under their latest name are still known by that name.
+	if networkID, ok := t.latest.ItemNameToRuntimeID(name); ok {
+		return protocol.ItemType{NetworkID: networkID, MetadataValue: metadata}, true
 	}
+	return protocol.ItemType{}, false
 }
 
 func (t *DefaultItemTranslator) UpgradeItemStack(input protocol.ItemStack) protocol.ItemStack {
 	if t.latest == t.mapping {
 		return input
 	}
-	input.ItemType = t.UpgradeItemType(input.ItemType)
+	if itemType, nbt, ok := t.upgradeSubstitute(input.ItemType, input.NBTData); ok {
+		// The item was substituted when it was sent to the client, so the original item is restored exactly.
+		input.ItemType, input.NBTData = itemType, nbt
+	} else {
+		input.ItemType = t.UpgradeItemType(input.ItemType)
+	}
+	input.NBTData = t.upgradeNBT(input.NBTData)
 
 	blockRuntimeId := uint32(0)
 	if input.NetworkID != t.latest.Air() {
@@ -236,6 +330,26 @@ func (t *DefaultItemTranslator) UpgradeItemStack(input protocol.ItemStack) proto
 	}
 }
 
+// upgradeSubstitute returns the latest item type and NBT of an item that was substituted for the legacy client,
+// using the legacy item type and NBT the client sent. The original item named in the NBT is only trusted if it is
+// substituted by the legacy item type sent, so that clients can't turn items into any item they like. False is
+// returned if the item wasn't substituted.
+func (t *DefaultItemTranslator) upgradeSubstitute(legacy protocol.ItemType, data map[string]any) (protocol.ItemType, map[string]any, bool) {
+	name, metadata, nbt, ok := restoreSubstitute(data)
+	if !ok {
+		return protocol.ItemType{}, data, false
+	}
+	networkID, ok := t.latest.ItemNameToRuntimeID(name)
+	if !ok {
+		return protocol.ItemType{}, data, false
+	}
+	original := protocol.ItemType{NetworkID: networkID, MetadataValue: metadata}
+	if substitute, substituted := t.downgradeItemType(original); !substituted || substitute != legacy {
+		return protocol.ItemType{}, data, false
+	}
+	return original, nbt, true
+}
+
 func (t *DefaultItemTranslator) UpgradeItemInstance(input protocol.ItemInstance) protocol.ItemInstance {
 	if t.latest == t.mapping {
 		return input
@@ -316,6 +430,10 @@ func (t *DefaultItemTranslator) DowngradeItemPackets(pks []packet.Packet, _ *min
 			pk.Item = t.DowngradeItemInstance(pk.Item)
 		case *packet.AddPlayer:
 			pk.HeldItem = t.DowngradeItemInstance(pk.HeldItem)
+		case *packet.AddActor:
+			t.downgradeEntityMetadata(pk.EntityMetadata)
+		case *packet.SetActorData:
+			t.downgradeEntityMetadata(pk.EntityMetadata)
 		case *packet.InventorySlot:
 			pk.NewItem = t.DowngradeItemInstance(pk.NewItem)
 			pk.StorageItem = t.DowngradeItemInstance(pk.StorageItem)
@@ -336,6 +454,17 @@ func (t *DefaultItemTranslator) DowngradeItemPackets(pks []packet.Packet, _ *min
 				}
 			}
 		case *packet.CraftingData:
+			if t.latest != t.mapping {
+				pk.Recipes = lo.Filter(pk.Recipes, func(recipe protocol.Recipe, _ int) bool {
+					return t.recipeRepresentable(recipe)
+				})
+				pk.PotionRecipes = lo.Filter(pk.PotionRecipes, func(recipe protocol.PotionRecipe, _ int) bool {
+					return t.potionRecipeRepresentable(recipe)
+				})
+				pk.PotionContainerChangeRecipes = lo.Filter(pk.PotionContainerChangeRecipes, func(recipe protocol.PotionContainerChangeRecipe, _ int) bool {
+					return t.containerChangeRecipeRepresentable(recipe)
+				})
+			}
 			for i, recipe := range pk.Recipes {
 				switch recipe := recipe.(type) {
 				case *protocol.ShapelessRecipe:
@@ -744,6 +873,24 @@ func (t *DefaultItemTranslator) UpgradeItemPackets(pks []packet.Packet, _ *minec
 	return result
 }
 
+// downgradeEntityMetadata downgrades the items held in entity metadata, such as the firework item that firework
+// rockets hold in place of a display block.
+func (t *DefaultItemTranslator) downgradeEntityMetadata(metadata map[uint32]any) {
+	if t.latest == t.mapping {
+		return
+	}
+	itemData, ok := metadata[protocol.EntityDataKeyDisplayTileRuntimeID].(map[string]any)
+	if !ok {
+		return
+	}
+	itemData = t.downgradeNBT(itemData)
+	if !isItemCompound(itemData) {
+		// The count may be left out of items held in metadata, in which case the item wasn't downgraded yet.
+		t.downgradeItemCompound(itemData)
+	}
+	metadata[protocol.EntityDataKeyDisplayTileRuntimeID] = itemData
+}
+
 func (t *DefaultItemTranslator) Register(item world.CustomItem, replacement string) {
 	name, _ := item.EncodeItem()
 	originalRid, ok := t.latest.ItemNameToRuntimeID(replacement)
@@ -0,0 +1,173 @@
+package legacyver
+
+import (
+	"fmt"
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"github.com/akmalfairuz/legacy-version/mapping"
+	"github.com/df-mc/worldupgrader/blockupgrader"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"sync"
+)
+
+// mappingCache holds the block, item and biome mappings parsed from the embedded data, so that protocols of the same
+// version share them instead of each decoding their own copy. A mapping is only decoded the first time it is
+// requested. Block and item mappings are handed out as copies, as they are adjusted for the custom blocks and items
+// of a server.
+type mappingCache struct {
+	mu     sync.Mutex
+	blocks map[string]*mapping.DefaultBlockMapping
+	items  map[string]*mapping.DefaultItemMapping
+	biomes map[string]*mapping.DefaultBiomeMapping
+}
+
+// mappings is the process-wide mapping cache used by all protocol constructors.
+var mappings = &mappingCache{
+	blocks: make(map[string]*mapping.DefaultBlockMapping),
+	items:  make(map[string]*mapping.DefaultItemMapping),
+	biomes: make(map[string]*mapping.DefaultBiomeMapping),
+}
+
+// block returns a copy of the block mapping stored under the key passed, decoding it from raw if it isn't cached
+// yet. The copy has the block actors remapped for the protocol ID passed. Every call returns a new copy, so that
+// adjusting the mapping for the custom blocks of one server doesn't affect the other protocols using the same data.
+func (c *mappingCache) block(key string, raw []byte, id int32) *mapping.DefaultBlockMapping {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, ok := c.blocks[key]
+	if !ok {
+		m = mapping.NewBlockMapping(raw)
+		c.blocks[key] = m
+	}
+	return m.Clone().WithBlockActorRemapper(blockActorRemapper(id))
+}
+
+// item returns a copy of the item mapping stored under the key passed, decoding it from the data passed if it
+// isn't cached yet. Every call returns a new copy, so that the items registered by one protocol aren't registered
+// for the other protocols using the same data.
+func (c *mappingCache) item(key string, itemRuntimeIDData, requiredItemList []byte, itemVersion uint16) *mapping.DefaultItemMapping {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key = fmt.Sprintf("%v@%v", key, itemVersion)
+	m, ok := c.items[key]
+	if !ok {
+		m = mapping.NewItemMapping(itemRuntimeIDData, requiredItemList, itemVersion, false)
+		c.items[key] = m
+	}
+	return m.Clone()
+}
+
+// biome returns the biome mapping stored under the key passed, decoding it from raw if it isn't cached yet.
+func (c *mappingCache) biome(key string, raw []byte) *mapping.DefaultBiomeMapping {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m, ok := c.biomes[key]; ok {
+		return m
+	}
+	m := mapping.NewBiomeMapping(raw)
+	c.biomes[key] = m
+	return m
+}
+
+// latestBlocks returns the block mapping of the latest version. Unlike the legacy block mappings, a single
+// instance is shared by all protocols and by LatestNetworkPersistentEncoding and LatestBlockPaletteEncoding, so
+// that the custom blocks the translators adjust it for are known to the package level encodings too.
+func latestBlocks() *mapping.DefaultBlockMapping {
+	return lazyLatestMapping.load()
+}
+
+// latestItems returns a copy of the item mapping of the latest version.
+func latestItems() *mapping.DefaultItemMapping {
+	return mappings.item("766", itemRuntimeIDData766, requiredItemList766, ItemVersion766)
+}
+
+// latestBiomes returns the biome mapping of the latest version.
+func latestBiomes() *mapping.DefaultBiomeMapping {
+	return mappings.biome("766", biomeData766)
+}
+
+// MappingStats holds statistics about the block, item and biome mappings currently held by the mapping cache.
+type MappingStats struct {
+	// BlockMappings is the amount of block mappings that are currently decoded.
+	BlockMappings int
+	// BlockStates is the total amount of block states held by the decoded block mappings.
+	BlockStates int
+	// ItemMappings is the amount of item mappings that are currently decoded.
+	ItemMappings int
+	// Items is the total amount of item entries held by the decoded item mappings.
+	Items int
+	// BiomeMappings is the amount of biome mappings that are currently decoded.
+	BiomeMappings int
+	// Biomes is the total amount of biomes held by the decoded biome mappings.
+	Biomes int
+}
+
+// CachedMappings returns statistics about the mappings that are currently decoded and held by the mapping
+// cache. It may be used to measure how much mapping data the process keeps in memory.
+func CachedMappings() MappingStats {
+	mappings.mu.Lock()
+	defer mappings.mu.Unlock()
+
+	stats := MappingStats{BlockMappings: len(mappings.blocks), ItemMappings: len(mappings.items), BiomeMappings: len(mappings.biomes)}
+	for _, m := range mappings.blocks {
+		stats.BlockStates += m.Len()
+	}
+	for _, m := range mappings.items {
+		stats.Items += m.Len()
+	}
+	for _, m := range mappings.biomes {
+		stats.Biomes += m.Len()
+	}
+	return stats
+}
+
+// ReleaseMappings drops all mappings held by the mapping cache, so that their memory may be reclaimed once no
+// Protocol uses them anymore. Protocols that were already constructed keep the mappings they use, and the
+// Protocols returned by All, ByID, ByVersion and Range stay cached. Protocols constructed after calling
+// ReleaseMappings decode their mappings again, except for the latest block mapping, which is shared by all of them.
+func ReleaseMappings() {
+	mappings.mu.Lock()
+	defer mappings.mu.Unlock()
+
+	mappings.blocks = make(map[string]*mapping.DefaultBlockMapping)
+	mappings.items = make(map[string]*mapping.DefaultItemMapping)
+	mappings.biomes = make(map[string]*mapping.DefaultBiomeMapping)
+}
+
+// lazyLatestBlockMapping is a mapping.Block that resolves to the latest block mapping the first time it is used.
+// It allows package level encodings to refer to the latest mapping without decoding it on initialisation.
+type lazyLatestBlockMapping struct {
+	once sync.Once
+	m    *mapping.DefaultBlockMapping
+}
+
+// load returns the latest block mapping, decoding it if needed.
+func (l *lazyLatestBlockMapping) load() *mapping.DefaultBlockMapping {
+	l.once.Do(func() {
+		l.m = mappings.block("766", blockStateData766, proto.ID766)
+	})
+	return l.m
+}
+
+func (l *lazyLatestBlockMapping) StateToRuntimeID(state blockupgrader.BlockState) (uint32, bool) {
+	return l.load().StateToRuntimeID(state)
+}
+
+func (l *lazyLatestBlockMapping) RuntimeIDToState(runtimeID uint32) (blockupgrader.BlockState, bool) {
+	return l.load().RuntimeIDToState(runtimeID)
+}
+
+func (l *lazyLatestBlockMapping) DowngradeBlockActorData(actorData map[string]any) {
+	l.load().DowngradeBlockActorData(actorData)
+}
+
+func (l *lazyLatestBlockMapping) UpgradeBlockActorData(actorData map[string]any) {
+	l.load().UpgradeBlockActorData(actorData)
+}
+
+func (l *lazyLatestBlockMapping) Adjust(entries []protocol.BlockEntry) {
+	l.load().Adjust(entries)
+}
+
+func (l *lazyLatestBlockMapping) Air() uint32 {
+	return l.load().Air()
+}
@@ -0,0 +1,110 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"maps"
+	"slices"
+)
+
+// packetAdditions holds the packets added since the oldest supported version, with the protocol version in which
+// each packet was added. Packets that aren't listed exist in every supported version.
+var packetAdditions = map[uint32]int32{
+	packet.IDAwardAchievement:         proto.ID685,
+	packet.IDClientBoundCloseForm:     proto.ID686,
+	packet.IDServerBoundLoadingScreen: proto.ID712,
+	packet.IDJigsawStructureData:      proto.ID712,
+	packet.IDCurrentStructureFeature:  proto.ID712,
+	packet.IDServerBoundDiagnostics:   proto.ID712,
+	packet.IDCameraAimAssist:          proto.ID712,
+	packet.IDContainerRegistryCleanup: proto.ID712,
+	packet.IDMovementEffect:           proto.ID748,
+	packet.IDSetMovementAuthority:     proto.ID748,
+	packet.IDCameraAimAssistPresets:   proto.ID766,
+}
+
+// PacketEmulator returns the packets sent in place of a packet of the latest version that doesn't exist in the
+// version of a Protocol. The packets returned are translated like any other packet, and those that the version
+// doesn't know either are dropped. Returning no packets drops the packet.
+type PacketEmulator func(pk packet.Packet, conn *minecraft.Conn) []packet.Packet
+
+// SupportsPacket checks if the packet with the ID passed exists in the version of the protocol.
+func (p *Protocol) SupportsPacket(id uint32) bool {
+	version, ok := packetAdditions[id]
+	return !ok || version <= p.id
+}
+
+// KnownPackets returns the IDs of all packets that exist in the version of the protocol, in ascending order.
+func (p *Protocol) KnownPackets() []uint32 {
+	ids := make([]uint32, 0, len(packetPoolServer))
+	for id := range packetPoolServer {
+		if p.SupportsPacket(id) {
+			ids = append(ids, id)
+		}
+	}
+	for id := range packetPoolClient {
+		if _, ok := packetPoolServer[id]; !ok && p.SupportsPacket(id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// SetPacketEmulator sets the PacketEmulator used for packets with the ID passed that don't exist in the version of
+// the protocol. Without an emulator, such packets are dropped. Passing a nil emulator removes the emulator.
+func (p *Protocol) SetPacketEmulator(id uint32, emulator PacketEmulator) {
+	p.emulatorsMu.Lock()
+	defer p.emulatorsMu.Unlock()
+	emulators := maps.Clone(p.emulators)
+	if emulators == nil {
+		emulators = make(map[uint32]PacketEmulator)
+	}
+	if emulator == nil {
+		delete(emulators, id)
+	} else {
+		emulators[id] = emulator
+	}
+	p.emulators = emulators
+}
+
+// packetEmulator returns the PacketEmulator set for the packet with the ID passed, if any.
+func (p *Protocol) packetEmulator(id uint32) (PacketEmulator, bool) {
+	p.emulatorsMu.Lock()
+	defer p.emulatorsMu.Unlock()
+	emulator, ok := p.emulators[id]
+	return emulator, ok
+}
+
+// supportedPackets returns the packets passed that exist in the version of the protocol, replacing the others by
+// the packets returned by their emulator, if they have one.
+func (p *Protocol) supportedPackets(pks []packet.Packet, conn *minecraft.Conn) []packet.Packet {
+	result := make([]packet.Packet, 0, len(pks))
+	for _, pk := range pks {
+		if p.SupportsPacket(pk.ID()) {
+			result = append(result, pk)
+			continue
+		}
+		if emulator, ok := p.packetEmulator(pk.ID()); ok {
+			for _, emulated := range emulator(pk, conn) {
+				if p.SupportsPacket(emulated.ID()) {
+					result = append(result, emulated)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// supportedPool returns a copy of the packet pool passed that only holds the packets that exist in the version of
+// the protocol.
+func (p *Protocol) supportedPool(pool packet.Pool) packet.Pool {
+	supported := make(packet.Pool, len(pool))
+	for id, f := range pool {
+		if p.SupportsPacket(id) {
+			supported[id] = f
+		}
+	}
+	return supported
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"sync"
 )
 
 var (
@@ -87,6 +88,9 @@ func convertPacketFunc(pid uint32, cur func() packet.Packet) func() packet.Packe
 	case packet.IDCodeBuilderSource:
 		return func() packet.Packet { return &legacypacket.CodeBuilderSource{} }
 	default:
+		// AvailableCommands and CommandRequest aren't listed, as every supported version encodes them the same: the
+		// parameter type IDs, the enum and parameter option flags and the chained subcommands, added in 1.20.10,
+		// haven't changed since 1.20.80.
 		return cur
 	}
 }
@@ -97,6 +101,23 @@ type Protocol struct {
 
 	blockTranslator BlockTranslator
 	itemTranslator  ItemTranslator
+	soundTranslator SoundTranslator
+
+	entitiesMu sync.Mutex
+	// entities holds the entity substitutions of the protocol. It is nil until it is first used.
+	entities map[string]string
+
+	emulatorsMu sync.Mutex
+	// emulators holds the PacketEmulators of packets that don't exist in the version of the protocol.
+	emulators map[uint32]PacketEmulator
+
+	cameraMu sync.Mutex
+	// cameraReporter is called for camera presets and instructions that the version of the protocol doesn't fully
+	// support. It is nil if no reporter was set.
+	cameraReporter CameraReporter
+
+	poolsOnce              sync.Once
+	clientPool, serverPool packet.Pool
 }
 
 func (p *Protocol) Ver() string {
@@ -107,11 +128,21 @@ func (p *Protocol) ID() int32 {
 	return p.id
 }
 
+// BlockTranslator returns the BlockTranslator used to translate block packets of the protocol.
+func (p *Protocol) BlockTranslator() BlockTranslator {
+	return p.blockTranslator
+}
+
+// Packets returns the packets that may be read from a connection using the protocol. Only packets that exist in
+// the version of the protocol are included.
 func (p *Protocol) Packets(listener bool) packet.Pool {
+	p.poolsOnce.Do(func() {
+		p.clientPool, p.serverPool = p.supportedPool(packetPoolClient), p.supportedPool(packetPoolServer)
+	})
 	if listener {
-		return packetPoolClient
+		return p.clientPool
 	}
-	return packetPoolServer
+	return p.serverPool
 }
 
 func (p *Protocol) NewReader(r minecraft.ByteReader, shieldID int32, enableLimits bool) protocol.IO {
@@ -124,41 +155,46 @@ func (p *Protocol) NewWriter(w minecraft.ByteWriter, shieldID int32) protocol.IO
 
 func (p *Protocol) ConvertToLatest(pk packet.Packet, conn *minecraft.Conn) []packet.Packet {
 	return p.blockTranslator.UpgradeBlockPackets(
-		p.itemTranslator.UpgradeItemPackets(p.upgradePackets([]packet.Packet{pk}, conn), conn),
+		p.itemTranslator.UpgradeItemPackets(
+			p.soundTranslator.UpgradeSoundPackets(p.upgradePackets([]packet.Packet{pk}, conn), conn),
+			conn),
 		conn)
 }
 
 func (p *Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []packet.Packet {
-	return p.downgradePackets(p.blockTranslator.DowngradeBlockPackets(
-		p.itemTranslator.DowngradeItemPackets([]packet.Packet{pk}, conn),
-		conn), conn)
+	// Packets that don't exist in the version are dropped or emulated before anything else, so that the translators
+	// only handle packets that are sent.
+	pks := p.emulateCameras(p.supportedPackets([]packet.Packet{pk}, conn), conn)
+	if len(pks) == 0 {
+		return nil
+	}
+	// Sounds are translated after blocks, as the block translator relies on the latest sound and event types.
+	return p.downgradePackets(p.soundTranslator.DowngradeSoundPackets(p.blockTranslator.DowngradeBlockPackets(
+		p.itemTranslator.DowngradeItemPackets(pks, conn),
+		conn), conn), conn)
 }
 
 func (p *Protocol) downgradePackets(pks []packet.Packet, conn *minecraft.Conn) []packet.Packet {
 	for pkIndex, pk := range pks {
 		switch pk := pk.(type) {
-		case *packet.ClientCacheStatus:
-			pk.Enabled = false // TODO: enable when chunk translation is not broken
-		case *packet.CameraPresets:
-			presets := make([]proto.CameraPreset, len(pk.Presets))
-			for i, p := range pk.Presets {
-				presets[i] = (&proto.CameraPreset{}).FromLatest(p)
-			}
-			pks[pkIndex] = &legacypacket.CameraPresets{
-				Presets: presets,
-			}
+		case *packet.SetActorData:
+			pk.EntityMetadata = downgradeEntityMetadata(p.id, pk.EntityMetadata)
+		case *packet.AvailableActorIdentifiers:
+			pk.SerialisedEntityIdentifiers = p.downgradeActorIdentifiers(pk.SerialisedEntityIdentifiers)
+		case *packet.AddItemActor:
+			pk.EntityMetadata = downgradeEntityMetadata(p.id, pk.EntityMetadata)
+		case *packet.SubChunk:
+			pk.SubChunkEntries = p.downgradeSubChunkHashes(pk, conn)
+		case *packet.ClientCacheMissResponse:
+			pk.Blobs = p.downgradeCacheBlobs(pk.Blobs, conn)
 		case *packet.PlayerAuthInput:
-			inputData := pk.InputData
-			if p.ID() < proto.ID766 {
-				inputData = fitBitset(inputData, 64)
-			}
 			pks[pkIndex] = &legacypacket.PlayerAuthInput{
 				Pitch:                  pk.Pitch,
 				Yaw:                    pk.Yaw,
 				Position:               pk.Position,
 				MoveVector:             pk.MoveVector,
 				HeadYaw:                pk.HeadYaw,
-				InputData:              inputData,
+				InputData:              downgradeInputData(p.id, pk.InputData),
 				InputMode:              pk.InputMode,
 				PlayMode:               pk.PlayMode,
 				InteractionModel:       pk.InteractionModel,
@@ -182,6 +218,7 @@ func (p *Protocol) downgradePackets(pks []packet.Packet, conn *minecraft.Conn) [
 			}
 			pks[pkIndex] = &legacypacket.ItemStackResponse{Responses: responses}
 		case *packet.ResourcePacksInfo:
+			pk.TexturePacks = p.downgradeTexturePacks(pk.TexturePacks)
 			texturePacks := make([]proto.TexturePackInfo, len(pk.TexturePacks))
 			packURLs := make([]protocol.PackURL, 0)
 			for i, t := range pk.TexturePacks {
@@ -202,6 +239,8 @@ func (p *Protocol) downgradePackets(pks []packet.Packet, conn *minecraft.Conn) [
 				TexturePacks:         texturePacks,
 				PackURLs:             packURLs,
 			}
+		case *packet.ResourcePackStack:
+			pk.TexturePacks = p.downgradeStackPacks(pk.TexturePacks)
 		case *packet.InventorySlot:
 			pks[pkIndex] = &legacypacket.InventorySlot{
 				WindowID:             pk.WindowID,
@@ -264,6 +303,13 @@ func (p *Protocol) downgradePackets(pks []packet.Packet, conn *minecraft.Conn) [
 				PlatformID:      pk.PlatformID,
 				Flags:           pk.Flags,
 			}
+		case *packet.ModalFormRequest:
+			data, translation := downgradeForm(pk.FormData)
+			stateOf(conn).setForm(pk.FormID, translation)
+			pks[pkIndex] = &packet.ModalFormRequest{
+				FormID:   pk.FormID,
+				FormData: data,
+			}
 		case *packet.Transfer:
 			pks[pkIndex] = &legacypacket.Transfer{
 				Address:     pk.Address,
@@ -275,10 +321,15 @@ func (p *Protocol) downgradePackets(pks []packet.Packet, conn *minecraft.Conn) [
 			for i, l := range pk.EntityLinks {
 				links[i] = (&proto.EntityLink{}).FromLatest(l)
 			}
+			entityType, substituted := p.downgradeEntityType(pk.EntityType)
+			if substituted {
+				// The properties are those of the original entity, which the replacement doesn't have.
+				pk.EntityProperties = protocol.EntityProperties{}
+			}
 			pks[pkIndex] = &legacypacket.AddActor{
 				EntityUniqueID:   pk.EntityUniqueID,
 				EntityRuntimeID:  pk.EntityRuntimeID,
-				EntityType:       pk.EntityType,
+				EntityType:       entityType,
 				Position:         pk.Position,
 				Velocity:         pk.Velocity,
 				Pitch:            pk.Pitch,
@@ -286,7 +337,7 @@ func (p *Protocol) downgradePackets(pks []packet.Packet, conn *minecraft.Conn) [
 				HeadYaw:          pk.HeadYaw,
 				BodyYaw:          pk.BodyYaw,
 				Attributes:       pk.Attributes,
-				EntityMetadata:   pk.EntityMetadata,
+				EntityMetadata:   downgradeEntityMetadata(p.id, pk.EntityMetadata),
 				EntityProperties: pk.EntityProperties,
 				EntityLinks:      links,
 			}
@@ -307,7 +358,7 @@ func (p *Protocol) downgradePackets(pks []packet.Packet, conn *minecraft.Conn) [
 				HeadYaw:          pk.HeadYaw,
 				HeldItem:         pk.HeldItem,
 				GameType:         pk.GameType,
-				EntityMetadata:   pk.EntityMetadata,
+				EntityMetadata:   downgradeEntityMetadata(p.id, pk.EntityMetadata),
 				EntityProperties: pk.EntityProperties,
 				AbilityData:      pk.AbilityData,
 				EntityLinks:      links,
@@ -527,6 +578,11 @@ func (p *Protocol) downgradePackets(pks []packet.Packet, conn *minecraft.Conn) [
 				Category:   pk.Category,
 				CodeStatus: pk.CodeStatus,
 			}
+		case *packet.LevelChunk:
+			// The packet may be shared with other connections, so the hashes are set on a copy.
+			chunk := *pk
+			chunk.BlobHashes = p.downgradeBlobHashes(pk.BlobHashes, conn, stateOf(conn).dimensionRange(pk.Dimension))
+			pks[pkIndex] = &chunk
 		}
 	}
 
@@ -536,8 +592,12 @@ func (p *Protocol) downgradePackets(pks []packet.Packet, conn *minecraft.Conn) [
 func (p *Protocol) upgradePackets(pks []packet.Packet, conn *minecraft.Conn) []packet.Packet {
 	for pkIndex, pk := range pks {
 		switch pk := pk.(type) {
-		case *packet.ClientCacheStatus:
-			pk.Enabled = false // TODO: enable when chunk translation is not broken
+		case *packet.SetActorData:
+			pk.EntityMetadata = upgradeEntityMetadata(p.id, pk.EntityMetadata)
+		case *packet.ClientCacheBlobStatus:
+			// Missing blobs are still looked up when the server sends them, but hits are never referred to again.
+			pk.MissHashes = p.upgradeBlobHashes(pk.MissHashes, conn, false)
+			pk.HitHashes = p.upgradeBlobHashes(pk.HitHashes, conn, true)
 		case *legacypacket.CameraPresets:
 			presets := make([]protocol.CameraPreset, len(pk.Presets))
 			for i, p := range pk.Presets {
@@ -550,13 +610,16 @@ func (p *Protocol) upgradePackets(pks []packet.Packet, conn *minecraft.Conn) []p
 			pk.GameVersion = p.ver
 			pk.BaseGameVersion = p.ver
 		case *legacypacket.PlayerAuthInput:
+			state := stateOf(conn)
+			inputData := upgradeInputData(p.id, pk.InputData, state.lastInput())
+			state.setInput(inputData)
 			pks[pkIndex] = &packet.PlayerAuthInput{
 				Pitch:                  pk.Pitch,
 				Yaw:                    pk.Yaw,
 				Position:               pk.Position,
 				MoveVector:             pk.MoveVector,
 				HeadYaw:                pk.HeadYaw,
-				InputData:              fitBitset(pk.InputData, packet.PlayerAuthInputBitsetSize),
+				InputData:              inputData,
 				InputMode:              pk.InputMode,
 				PlayMode:               pk.PlayMode,
 				InteractionModel:       pk.InteractionModel,
@@ -660,6 +723,12 @@ func (p *Protocol) upgradePackets(pks []packet.Packet, conn *minecraft.Conn) []p
 				PlatformID:      pk.PlatformID,
 				Flags:           pk.Flags,
 			}
+		case *packet.ModalFormResponse:
+			if translation, ok := stateOf(conn).takeForm(pk.FormID); ok {
+				if data, ok := pk.ResponseData.Value(); ok {
+					pk.ResponseData = protocol.Option(upgradeFormResponse(data, translation))
+				}
+			}
 		case *legacypacket.Transfer:
 			pks[pkIndex] = &packet.Transfer{
 				Address:     pk.Address,
@@ -682,7 +751,7 @@ func (p *Protocol) upgradePackets(pks []packet.Packet, conn *minecraft.Conn) []p
 				HeadYaw:          pk.HeadYaw,
 				BodyYaw:          pk.BodyYaw,
 				Attributes:       pk.Attributes,
-				EntityMetadata:   pk.EntityMetadata,
+				EntityMetadata:   upgradeEntityMetadata(p.id, pk.EntityMetadata),
 				EntityProperties: pk.EntityProperties,
 				EntityLinks:      links,
 			}
@@ -703,7 +772,7 @@ func (p *Protocol) upgradePackets(pks []packet.Packet, conn *minecraft.Conn) []p
 				HeadYaw:          pk.HeadYaw,
 				HeldItem:         pk.HeldItem,
 				GameType:         pk.GameType,
-				EntityMetadata:   pk.EntityMetadata,
+				EntityMetadata:   upgradeEntityMetadata(p.id, pk.EntityMetadata),
 				EntityProperties: pk.EntityProperties,
 				AbilityData:      pk.AbilityData,
 				EntityLinks:      links,
@@ -0,0 +1,94 @@
+package legacyver
+
+import (
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// itemRepresentable checks if the latest item type passed can be represented in the legacy version, either by the
+// item itself, a custom item or its substitute. Items that would be replaced by the placeholder item can't be.
+func (t *DefaultItemTranslator) itemRepresentable(input protocol.ItemType) bool {
+	itemType, substituted := t.downgradeItemType(input)
+	if !substituted {
+		return true
+	}
+	placeholder, _ := t.mapping.ItemNameToRuntimeID(itemPlaceholder)
+	return itemType.NetworkID != placeholder
+}
+
+// descriptorRepresentable checks if the items matched by the latest item descriptor passed can be represented in
+// the legacy version. Descriptors matching items through tags or Molang expressions always can.
+func (t *DefaultItemTranslator) descriptorRepresentable(input protocol.ItemDescriptor) bool {
+	switch descriptor := input.(type) {
+	case *protocol.DefaultItemDescriptor:
+		return t.itemRepresentable(protocol.ItemType{NetworkID: int32(descriptor.NetworkID), MetadataValue: uint32(descriptor.MetadataValue)})
+	case *protocol.DeferredItemDescriptor:
+		return t.nameRepresentable(descriptor.Name)
+	case *protocol.ComplexAliasItemDescriptor:
+		return t.nameRepresentable(descriptor.Name)
+	}
+	return true
+}
+
+// nameRepresentable checks if the latest item with the name passed can be represented in the legacy version.
+func (t *DefaultItemTranslator) nameRepresentable(name string) bool {
+	rid, ok := t.latest.ItemNameToRuntimeID(name)
+	return ok && t.itemRepresentable(protocol.ItemType{NetworkID: rid})
+}
+
+// recipeRepresentable checks if all inputs and outputs of the latest recipe passed can be represented in the legacy
+// version. Recipes that can't are not sent to the client, as they would show up as recipes of placeholder items.
+// The recipes that are sent keep their RecipeNetworkID, so that the craft actions of the client still refer to the
+// recipe of the server.
+func (t *DefaultItemTranslator) recipeRepresentable(recipe protocol.Recipe) bool {
+	var (
+		inputs  []protocol.ItemDescriptorCount
+		outputs []protocol.ItemStack
+	)
+	switch recipe := recipe.(type) {
+	case *protocol.ShapelessRecipe:
+		inputs, outputs = recipe.Input, recipe.Output
+	case *protocol.ShapedRecipe:
+		inputs, outputs = recipe.Input, recipe.Output
+	case *protocol.ShulkerBoxRecipe:
+		inputs, outputs = recipe.Input, recipe.Output
+	case *protocol.ShapelessChemistryRecipe:
+		inputs, outputs = recipe.Input, recipe.Output
+	case *protocol.ShapedChemistryRecipe:
+		inputs, outputs = recipe.Input, recipe.Output
+	case *protocol.FurnaceRecipe:
+		return t.itemRepresentable(recipe.InputType) && t.itemRepresentable(recipe.Output.ItemType)
+	case *protocol.FurnaceDataRecipe:
+		return t.itemRepresentable(recipe.InputType) && t.itemRepresentable(recipe.Output.ItemType)
+	case *protocol.SmithingTransformRecipe:
+		inputs, outputs = []protocol.ItemDescriptorCount{recipe.Template, recipe.Base, recipe.Addition}, []protocol.ItemStack{recipe.Result}
+	case *protocol.SmithingTrimRecipe:
+		inputs = []protocol.ItemDescriptorCount{recipe.Template, recipe.Base, recipe.Addition}
+	}
+	for _, input := range inputs {
+		if !t.descriptorRepresentable(input.Descriptor) {
+			return false
+		}
+	}
+	for _, output := range outputs {
+		if !t.itemRepresentable(output.ItemType) {
+			return false
+		}
+	}
+	return true
+}
+
+// potionRecipeRepresentable checks if the items of the latest potion recipe passed can be represented in the legacy
+// version.
+func (t *DefaultItemTranslator) potionRecipeRepresentable(recipe protocol.PotionRecipe) bool {
+	return t.itemRepresentable(protocol.ItemType{NetworkID: recipe.InputPotionID, MetadataValue: uint32(recipe.InputPotionMetadata)}) &&
+		t.itemRepresentable(protocol.ItemType{NetworkID: recipe.ReagentItemID, MetadataValue: uint32(recipe.ReagentItemMetadata)}) &&
+		t.itemRepresentable(protocol.ItemType{NetworkID: recipe.OutputPotionID, MetadataValue: uint32(recipe.OutputPotionMetadata)})
+}
+
+// containerChangeRecipeRepresentable checks if the items of the latest potion container change recipe passed can be
+// represented in the legacy version.
+func (t *DefaultItemTranslator) containerChangeRecipeRepresentable(recipe protocol.PotionContainerChangeRecipe) bool {
+	return t.itemRepresentable(protocol.ItemType{NetworkID: recipe.InputItemID}) &&
+		t.itemRepresentable(protocol.ItemType{NetworkID: recipe.ReagentItemID}) &&
+		t.itemRepresentable(protocol.ItemType{NetworkID: recipe.OutputItemID})
+}
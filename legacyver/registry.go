@@ -0,0 +1,79 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"sync"
+)
+
+// registryEntry holds a supported protocol version. The Protocol is only constructed the first time it is
+// requested, so that the mappings of versions that are never used are never decoded.
+type registryEntry struct {
+	id          int32
+	ver         string
+	newProtocol func() *Protocol
+
+	once sync.Once
+	p    *Protocol
+}
+
+// protocol returns the Protocol of the entry, constructing it if it wasn't constructed yet.
+func (e *registryEntry) protocol() *Protocol {
+	e.once.Do(func() {
+		e.p = e.newProtocol()
+	})
+	return e.p
+}
+
+// registry holds all supported protocol versions, ordered from the newest to the oldest.
+var registry = []*registryEntry{
+	{id: proto.ID766, ver: "1.21.50", newProtocol: New766},
+	{id: proto.ID748, ver: "1.21.40", newProtocol: New748},
+	{id: proto.ID729, ver: "1.21.30", newProtocol: New729},
+	{id: proto.ID712, ver: "1.21.20", newProtocol: New712},
+	{id: proto.ID686, ver: "1.21.2", newProtocol: New686},
+	{id: proto.ID685, ver: "1.21.0", newProtocol: New685},
+	{id: proto.ID671, ver: "1.20.80", newProtocol: New671},
+}
+
+// All returns every supported Protocol, ordered from the newest to the oldest. Every Protocol is constructed
+// once and the same instance is returned on subsequent calls.
+func All() []*Protocol {
+	protocols := make([]*Protocol, 0, len(registry))
+	for _, e := range registry {
+		protocols = append(protocols, e.protocol())
+	}
+	return protocols
+}
+
+// ByID returns the Protocol with the protocol ID passed. False is returned if the protocol is not supported.
+func ByID(id int32) (*Protocol, bool) {
+	for _, e := range registry {
+		if e.id == id {
+			return e.protocol(), true
+		}
+	}
+	return nil, false
+}
+
+// ByVersion returns the Protocol of the game version passed, such as "1.21.40". False is returned if the
+// version is not supported.
+func ByVersion(ver string) (*Protocol, bool) {
+	for _, e := range registry {
+		if e.ver == ver {
+			return e.protocol(), true
+		}
+	}
+	return nil, false
+}
+
+// Range returns every supported Protocol with a protocol ID between min and max (inclusive), ordered from
+// the newest to the oldest.
+func Range(min, max int32) []*Protocol {
+	var protocols []*Protocol
+	for _, e := range registry {
+		if e.id >= min && e.id <= max {
+			protocols = append(protocols, e.protocol())
+		}
+	}
+	return protocols
+}
@@ -0,0 +1,34 @@
+package legacyver
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+)
+
+func TestRegistryProtocols(t *testing.T) {
+	ids := func(protocols []*Protocol) []int32 {
+		s := make([]int32, 0, len(protocols))
+		for _, p := range protocols {
+			s = append(s, p.ID())
+		}
+		return s
+	}
+
+	want := []int32{proto.ID766, proto.ID748, proto.ID729, proto.ID712, proto.ID686, proto.ID685, proto.ID671}
+	if got := ids(All()); !slices.Equal(got, want) {
+		t.Fatalf("All() = %v, want %v", got, want)
+	}
+	if got := ids(Range(proto.ID685, proto.ID766)); !slices.Equal(got, want[:6]) {
+		t.Fatalf("Range(%v, %v) = %v, want %v", proto.ID685, proto.ID766, got, want[:6])
+	}
+	for _, p := range All() {
+		if byID, ok := ByID(p.ID()); !ok || byID != p {
+			t.Fatalf("ByID(%v) doesn't return the Protocol of All()", p.ID())
+		}
+		if byVer, ok := ByVersion(p.Ver()); !ok || byVer != p {
+			t.Fatalf("ByVersion(%v) doesn't return the Protocol of All()", p.Ver())
+		}
+	}
+}
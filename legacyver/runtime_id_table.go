@@ -0,0 +1,135 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/mapping"
+	"github.com/segmentio/fasthash/fnv1"
+	"sync"
+)
+
+// runtimeIDTable holds the precomputed translation of block runtime IDs between a legacy and the latest block
+// mapping, so that translating a runtime ID is a single slice lookup.
+type runtimeIDTable struct {
+	// legacyAdjustments and latestAdjustments are the adjustments of the mappings the table was built from.
+	legacyAdjustments, latestAdjustments uint64
+	// downgrade is indexed by latest runtime IDs and upgrade by legacy runtime IDs.
+	downgrade, upgrade   []uint32
+	legacyAir, latestAir uint32
+	// fallbacks holds the latest states that don't exist in the legacy mapping and their replacements.
+	fallbacks []BlockFallback
+
+	legacy, latest mapping.Block
+	// hashes holds the network hashes of the states of both mappings. They are only computed once a connection
+	// uses hashed block network IDs.
+	hashesOnce sync.Once
+	hashes     *blockHashTable
+	// fingerprint is a hash of the downgrade table, computed once it is first needed.
+	fingerprintOnce sync.Once
+	fingerprint     uint64
+}
+
+// adjustable is implemented by block mappings that keep track of the adjustments made to them, such as
+// mapping.DefaultBlockMapping.
+type adjustable interface {
+	Adjustments() uint64
+}
+
+// adjustments returns the amount of adjustments made to the block mapping passed, or 0 if it doesn't keep track
+// of them.
+func adjustments(m mapping.Block) uint64 {
+	if a, ok := m.(adjustable); ok {
+		return a.Adjustments()
+	}
+	return 0
+}
+
+// tracksAdjustments checks if the block mapping passed keeps track of the adjustments made to it.
+func tracksAdjustments(m mapping.Block) bool {
+	_, ok := m.(adjustable)
+	return ok
+}
+
+// newRuntimeIDTable builds the runtime ID tables between the legacy and latest block mappings passed. Latest states
+// that don't exist in the legacy mapping are replaced using the substitutions passed.
+func newRuntimeIDTable(legacy, latest mapping.Block, substitutions map[string]BlockSubstitution) *runtimeIDTable {
+	table := &runtimeIDTable{
+		legacyAdjustments: adjustments(legacy),
+		latestAdjustments: adjustments(latest),
+		upgrade:           buildRuntimeIDTable(legacy, latest),
+		legacyAir:         legacy.Air(),
+		latestAir:         latest.Air(),
+		legacy:            legacy,
+		latest:            latest,
+	}
+
+	var resolver *blockFallbackResolver
+	for rid := uint32(0); ; rid++ {
+		state, ok := latest.RuntimeIDToState(rid)
+		if !ok {
+			break
+		}
+		legacyRID, ok := legacy.StateToRuntimeID(state)
+		if !ok {
+			if resolver == nil {
+				resolver = newBlockFallbackResolver(legacy, latest, substitutions)
+			}
+			var kind BlockFallbackKind
+			legacyRID, kind = resolver.resolve(state)
+
+			replacement, _ := legacy.RuntimeIDToState(legacyRID)
+			table.fallbacks = append(table.fallbacks, BlockFallback{State: state, Replacement: replacement, Kind: kind})
+		}
+		table.downgrade = append(table.downgrade, legacyRID)
+	}
+	return table
+}
+
+// upToDate checks if the table still matches the block mappings passed.
+func (table *runtimeIDTable) upToDate(legacy, latest mapping.Block) bool {
+	return table.legacyAdjustments == adjustments(legacy) && table.latestAdjustments == adjustments(latest)
+}
+
+// downgradeRuntimeID translates a latest runtime ID to a legacy runtime ID.
+func (table *runtimeIDTable) downgradeRuntimeID(rid uint32) uint32 {
+	if rid >= uint32(len(table.downgrade)) {
+		return table.legacyAir
+	}
+	return table.downgrade[rid]
+}
+
+// upgradeRuntimeID translates a legacy runtime ID to a latest runtime ID.
+func (table *runtimeIDTable) upgradeRuntimeID(rid uint32) uint32 {
+	if rid >= uint32(len(table.upgrade)) {
+		return table.latestAir
+	}
+	return table.upgrade[rid]
+}
+
+// buildRuntimeIDTable builds a table indexed by the runtime IDs of the first mapping, holding the runtime IDs of
+// the same states in the second mapping. States that the second mapping doesn't have are translated to air.
+func buildRuntimeIDTable(from, to mapping.Block) []uint32 {
+	var table []uint32
+	for rid := uint32(0); ; rid++ {
+		state, ok := from.RuntimeIDToState(rid)
+		if !ok {
+			return table
+		}
+		translated, ok := to.StateToRuntimeID(state)
+		if !ok {
+			translated = to.Air()
+		}
+		table = append(table, translated)
+	}
+}
+
+// blobFingerprint returns a hash of the downgrade table, which changes whenever the runtime IDs of either mapping or
+// the substitutions of missing states change.
+func (table *runtimeIDTable) blobFingerprint() uint64 {
+	table.fingerprintOnce.Do(func() {
+		hash := fnv1.Init64
+		for _, rid := range table.downgrade {
+			hash = fnv1.AddUint64(hash, uint64(rid))
+		}
+		table.fingerprint = hash
+	})
+	return table.fingerprint
+}
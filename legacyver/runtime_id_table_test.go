@@ -0,0 +1,112 @@
+package legacyver
+
+import (
+	"testing"
+
+	"github.com/akmalfairuz/legacy-version/internal/chunk"
+	"github.com/akmalfairuz/legacy-version/mapping"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// benchmarkTranslator returns the block translator of 1.21.40 with its runtime ID tables built.
+func benchmarkTranslator(b *testing.B) *DefaultBlockTranslator {
+	b.Helper()
+	t := New748().blockTranslator.(*DefaultBlockTranslator)
+	t.runtimeIDTable()
+	return t
+}
+
+// latestStates returns the amount of block states of the latest version.
+func latestStates(t *DefaultBlockTranslator) uint32 {
+	return uint32(t.latest.(*mapping.DefaultBlockMapping).Len())
+}
+
+func BenchmarkDowngradeBlockRuntimeID(b *testing.B) {
+	t := benchmarkTranslator(b)
+	n := latestStates(t)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		t.DowngradeBlockRuntimeID(uint32(i) % n)
+	}
+}
+
+// BenchmarkDowngradeBlockRuntimeIDLookup measures the translation through state lookups that the runtime ID
+// tables replaced, for comparison with BenchmarkDowngradeBlockRuntimeID.
+func BenchmarkDowngradeBlockRuntimeIDLookup(b *testing.B) {
+	t := benchmarkTranslator(b)
+	n := latestStates(t)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		state, _ := t.latest.RuntimeIDToState(uint32(i) % n)
+		if _, ok := t.mapping.StateToRuntimeID(state); !ok {
+			_ = t.mapping.Air()
+		}
+	}
+}
+
+func BenchmarkUpgradeBlockRuntimeID(b *testing.B) {
+	t := benchmarkTranslator(b)
+	n := uint32(t.mapping.(*mapping.DefaultBlockMapping).Len())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		t.UpgradeBlockRuntimeID(uint32(i) % n)
+	}
+}
+
+// latestRuntimeID returns the runtime ID of the first state of the block with the name passed in the latest version.
+func latestRuntimeID(b *testing.B, t *DefaultBlockTranslator, name string) uint32 {
+	b.Helper()
+	for rid := uint32(0); rid < latestStates(t); rid++ {
+		if state, _ := t.latest.RuntimeIDToState(rid); state.Name == name {
+			return rid
+		}
+	}
+	b.Fatalf("no state of block %v", name)
+	return 0
+}
+
+func BenchmarkDowngradeSubChunk(b *testing.B) {
+	t := benchmarkTranslator(b)
+	// The sub chunk is filled like an underground one: mostly stone and deepslate with some dirt, gravel, ores and
+	// water, which leaves it with a small palette.
+	stone, deepslate := latestRuntimeID(b, t, "minecraft:stone"), latestRuntimeID(b, t, "minecraft:deepslate")
+	ores := []uint32{
+		latestRuntimeID(b, t, "minecraft:dirt"), latestRuntimeID(b, t, "minecraft:gravel"),
+		latestRuntimeID(b, t, "minecraft:coal_ore"), latestRuntimeID(b, t, "minecraft:iron_ore"),
+		latestRuntimeID(b, t, "minecraft:water"), t.latest.Air(),
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Downgrading replaces the palette of the sub chunk, so a new one is filled for every iteration.
+		b.StopTimer()
+		sub := chunk.NewSubChunk(t.latest.Air())
+		for j := 0; j < 4096; j++ {
+			x, y, z := byte(j&15), byte(j>>4&15), byte(j>>8)
+			rid := stone
+			if y < 6 {
+				rid = deepslate
+			}
+			if h := uint32(j) * 2654435761 >> 24; h < 24 {
+				rid = ores[h%uint32(len(ores))]
+			}
+			sub.SetBlock(x, y, z, 0, rid)
+		}
+		b.StartTimer()
+		t.DowngradeSubChunk(sub)
+	}
+}
+
+func TestStartGameKeepsRuntimeIDTables(t *testing.T) {
+	tr := New748().blockTranslator.(*DefaultBlockTranslator)
+	table := tr.runtimeIDTable()
+
+	tr.DowngradeBlockPackets([]packet.Packet{&packet.StartGame{}}, nil)
+	if tr.runtimeIDTable() != table {
+		t.Fatal("runtime ID tables were rebuilt by a StartGame without custom blocks")
+	}
+	tr.DowngradeBlockPackets([]packet.Packet{&packet.StartGame{Blocks: []protocol.BlockEntry{{Name: "custom:block", Properties: map[string]any{}}}}}, nil)
+	if tr.runtimeIDTable() == table {
+		t.Fatal("runtime ID tables were not rebuilt after custom blocks were added")
+	}
+}
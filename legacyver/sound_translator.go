@@ -0,0 +1,155 @@
+package legacyver
+
+import (
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"strings"
+)
+
+// SoundTranslator translates the sound and level events of LevelSoundEvent, LevelEvent and PlaySound packets
+// between the latest version and a legacy version. A Protocol passes all packets it converts through its
+// SoundTranslator, after the block translator when downgrading.
+type SoundTranslator interface {
+	// DowngradeSoundPackets downgrades the sounds and level events of the input packets to ones the legacy client
+	// knows.
+	DowngradeSoundPackets([]packet.Packet, *minecraft.Conn) (result []packet.Packet)
+	// UpgradeSoundPackets upgrades the sounds and level events of the input packets, such as the LevelSoundEvent
+	// packets sent by the client, to the latest version. Sound and level event IDs are only ever added, never
+	// renumbered, so every event a legacy client sends means the same in the latest version and
+	// DefaultSoundTranslator returns the packets unchanged.
+	UpgradeSoundPackets([]packet.Packet, *minecraft.Conn) (result []packet.Packet)
+}
+
+// dropEvent is the replacement of sound and level events that have no equivalent in older versions. Packets with
+// these events are not sent to the client.
+const dropEvent = ^uint32(0)
+
+// eventSubstitution describes a sound or level event that was added in a version of the game, and the event that
+// replaces it for older clients.
+type eventSubstitution struct {
+	// version is the protocol version in which the event was added.
+	version int32
+	// replacement is the event sent to older clients instead, or dropEvent if the event is not sent at all. If the
+	// replacement is newer than the client too, its own replacement is used.
+	replacement uint32
+}
+
+// soundEventSubstitutions holds the LevelSoundEvent sound types added since the oldest supported version.
+var soundEventSubstitutions = map[uint32]eventSubstitution{
+	packet.SoundEventBundleInsertFailed:                 {version: proto.ID748, replacement: dropEvent},
+	packet.SoundEventImitateDrowned:                     {version: proto.ID729, replacement: packet.SoundEventImitateZombie},
+	packet.SoundEventVaultRejectRewardedPlayer:          {version: proto.ID712, replacement: packet.SoundEventVaultInsertItemFail},
+	packet.SoundEventRecordPrecipice:                    {version: proto.ID685, replacement: packet.SoundEventRecordRelic},
+	packet.SoundEventRecordCreatorMusicBox:              {version: proto.ID685, replacement: packet.SoundEventRecordRelic},
+	packet.SoundEventRecordCreator:                      {version: proto.ID685, replacement: packet.SoundEventRecordRelic},
+	packet.SoundEventOminousItemSpawnerAboutToSpawnItem: {version: proto.ID685, replacement: dropEvent},
+	packet.SoundEventApplyEffectTrialOmen:               {version: proto.ID685, replacement: dropEvent},
+	packet.SoundEventApplyEffectRaidOmen:                {version: proto.ID685, replacement: dropEvent},
+	packet.SoundEventApplyEffectBadOmen:                 {version: proto.ID685, replacement: dropEvent},
+}
+
+// levelEventSubstitutions holds the LevelEvent event types added since the oldest supported version.
+var levelEventSubstitutions = map[uint32]eventSubstitution{
+	packet.LevelEventParticleCreakingHeartTrail:            {version: proto.ID766, replacement: dropEvent},
+	packet.LevelEventParticleSmashAttackGroundDust:         {version: proto.ID685, replacement: dropEvent},
+	packet.LevelEventAnimationSpawnCobweb:                  {version: proto.ID685, replacement: dropEvent},
+	packet.LevelEventParticlesTrialSpawnerBecomeCharged:    {version: proto.ID685, replacement: packet.LevelEventParticlesTrialSpawnerSpawning},
+	packet.LevelEventParticlesTrialSpawnerDetectionCharged: {version: proto.ID685, replacement: packet.LevelEventParticlesTrialSpawnerDetection},
+}
+
+// soundNameSubstitution describes sounds played through PlaySound that were added to the vanilla resource pack in a
+// version of the game.
+type soundNameSubstitution struct {
+	// version is the protocol version in which the sounds were added.
+	version int32
+	// prefix is the prefix shared by the names of the sounds.
+	prefix string
+	// replacement is the name of the sound played for older clients instead, or empty if no sound is played.
+	replacement string
+}
+
+// soundNameSubstitutions holds the sounds added to the vanilla resource pack since the oldest supported version.
+var soundNameSubstitutions = []soundNameSubstitution{
+	{version: proto.ID766, prefix: "mob.creaking.", replacement: "mob.zombie.say"},
+	{version: proto.ID766, prefix: "block.creaking_heart.", replacement: ""},
+	{version: proto.ID766, prefix: "block.eyeblossom.", replacement: ""},
+	{version: proto.ID748, prefix: "bundle.insert_fail", replacement: ""},
+	{version: proto.ID685, prefix: "mob.breeze.", replacement: "mob.blaze.breathe"},
+	{version: proto.ID685, prefix: "mob.bogged.", replacement: "mob.skeleton.say"},
+	{version: proto.ID685, prefix: "wind_charge.", replacement: "random.pop"},
+	{version: proto.ID685, prefix: "mace.", replacement: "random.anvil_land"},
+	{version: proto.ID685, prefix: "record.creator", replacement: "record.relic"},
+	{version: proto.ID685, prefix: "record.precipice", replacement: "record.relic"},
+}
+
+type DefaultSoundTranslator struct {
+	// id is the protocol version of the legacy client.
+	id int32
+}
+
+// NewSoundTranslator returns a DefaultSoundTranslator that translates sounds for the protocol version passed.
+func NewSoundTranslator(id int32) *DefaultSoundTranslator {
+	return &DefaultSoundTranslator{id: id}
+}
+
+func (t *DefaultSoundTranslator) DowngradeSoundPackets(pks []packet.Packet, _ *minecraft.Conn) (result []packet.Packet) {
+	for _, pk := range pks {
+		switch pk := pk.(type) {
+		case *packet.LevelSoundEvent:
+			soundType, ok := t.downgradeEvent(soundEventSubstitutions, pk.SoundType)
+			if !ok {
+				continue
+			}
+			pk.SoundType = soundType
+		case *packet.LevelEvent:
+			// Legacy particle events carry the particle ID in the event type, which isn't translated.
+			if pk.EventType&packet.LevelEventParticleLegacyEvent == 0 {
+				eventType, ok := t.downgradeEvent(levelEventSubstitutions, uint32(pk.EventType))
+				if !ok {
+					continue
+				}
+				pk.EventType = int32(eventType)
+			}
+		case *packet.PlaySound:
+			name, ok := t.downgradeSoundName(pk.SoundName)
+			if !ok {
+				continue
+			}
+			pk.SoundName = name
+		}
+		result = append(result, pk)
+	}
+	return result
+}
+
+func (t *DefaultSoundTranslator) UpgradeSoundPackets(pks []packet.Packet, _ *minecraft.Conn) (result []packet.Packet) {
+	// Events and sounds of legacy clients all exist in the latest version with the same IDs.
+	return pks
+}
+
+// downgradeEvent returns the event that replaces the event passed for the legacy client, using the substitution
+// table passed. False is returned if the event should not be sent at all.
+func (t *DefaultSoundTranslator) downgradeEvent(substitutions map[uint32]eventSubstitution, event uint32) (uint32, bool) {
+	for {
+		sub, ok := substitutions[event]
+		if !ok || sub.version <= t.id {
+			return event, true
+		}
+		if sub.replacement == dropEvent {
+			return 0, false
+		}
+		event = sub.replacement
+	}
+}
+
+// downgradeSoundName returns the name of the sound played for the legacy client in place of the sound name passed.
+// False is returned if no sound should be played.
+func (t *DefaultSoundTranslator) downgradeSoundName(name string) (string, bool) {
+	for _, sub := range soundNameSubstitutions {
+		if sub.version > t.id && strings.HasPrefix(name, sub.prefix) {
+			return sub.replacement, sub.replacement != ""
+		}
+	}
+	return name, true
+}
@@ -4,7 +4,6 @@ import (
 	_ "embed"
 	"github.com/akmalfairuz/legacy-version/internal/chunk"
 	"github.com/akmalfairuz/legacy-version/legacyver/proto"
-	"github.com/akmalfairuz/legacy-version/mapping"
 )
 
 const (
@@ -21,17 +20,23 @@ var (
 	requiredItemList671 []byte
 	//go:embed data/block_states_671.nbt
 	blockStateData671 []byte
+	//go:embed data/item_substitutions_671.json
+	itemSubstitutionData671 []byte
 )
 
 // New671 ...
 func New671() *Protocol {
-	itemMapping := mapping.NewItemMapping(itemRuntimeIDData671, requiredItemList671, ItemVersion671, false)
-	blockMapping := mapping.NewBlockMapping(blockStateData671)
+	itemMapping := mappings.item("671", itemRuntimeIDData671, requiredItemList671, ItemVersion671)
+	blockMapping := mappings.block("671", blockStateData671, proto.ID671)
+	latestBlockMapping := latestBlocks()
+	biomeMapping := mappings.biome("748", biomeData748)
+	blockTranslator := NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion671), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion671), false).WithBiomeMapping(biomeMapping, latestBiomes()).WithBlockSubstitutions(parseBlockSubstitutions(blockSubstitutionData729))
 
 	return &Protocol{
 		ver:             "1.20.80",
 		id:              proto.ID671,
-		blockTranslator: NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion671), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion671), false),
-		itemTranslator:  NewItemTranslator(itemMapping, itemMappingLatest, blockMapping, blockMappingLatest),
+		blockTranslator: blockTranslator,
+		itemTranslator:  NewItemTranslator(itemMapping, latestItems(), blockMapping, latestBlockMapping).WithItemSubstitutions(parseItemSubstitutions(itemSubstitutionData671)).WithBlockTranslator(blockTranslator),
+		soundTranslator: NewSoundTranslator(proto.ID671),
 	}
 }
@@ -4,7 +4,6 @@ import (
 	_ "embed"
 	"github.com/akmalfairuz/legacy-version/internal/chunk"
 	"github.com/akmalfairuz/legacy-version/legacyver/proto"
-	"github.com/akmalfairuz/legacy-version/mapping"
 )
 
 const (
@@ -16,13 +15,17 @@ const (
 
 // New685 uses same data as 686
 func New685() *Protocol {
-	itemMapping := mapping.NewItemMapping(itemRuntimeIDData686, requiredItemList686, ItemVersion685, false)
-	blockMapping := mapping.NewBlockMapping(blockStateData686)
+	itemMapping := mappings.item("686", itemRuntimeIDData686, requiredItemList686, ItemVersion685)
+	blockMapping := mappings.block("686", blockStateData686, proto.ID685)
+	latestBlockMapping := latestBlocks()
+	biomeMapping := mappings.biome("748", biomeData748)
+	blockTranslator := NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion685), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion685), false).WithBiomeMapping(biomeMapping, latestBiomes()).WithBlockSubstitutions(parseBlockSubstitutions(blockSubstitutionData729))
 
 	return &Protocol{
 		ver:             "1.21.0",
 		id:              proto.ID685,
-		blockTranslator: NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion685), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion685), false),
-		itemTranslator:  NewItemTranslator(itemMapping, itemMappingLatest, blockMapping, blockMappingLatest),
+		blockTranslator: blockTranslator,
+		itemTranslator:  NewItemTranslator(itemMapping, latestItems(), blockMapping, latestBlockMapping).WithItemSubstitutions(parseItemSubstitutions(itemSubstitutionData686)).WithBlockTranslator(blockTranslator),
+		soundTranslator: NewSoundTranslator(proto.ID685),
 	}
 }
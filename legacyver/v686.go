@@ -4,7 +4,6 @@ import (
 	_ "embed"
 	"github.com/akmalfairuz/legacy-version/internal/chunk"
 	"github.com/akmalfairuz/legacy-version/legacyver/proto"
-	"github.com/akmalfairuz/legacy-version/mapping"
 )
 
 const (
@@ -21,16 +20,22 @@ var (
 	requiredItemList686 []byte
 	//go:embed data/block_states_686.nbt
 	blockStateData686 []byte
+	//go:embed data/item_substitutions_686.json
+	itemSubstitutionData686 []byte
 )
 
 func New686() *Protocol {
-	itemMapping := mapping.NewItemMapping(itemRuntimeIDData686, requiredItemList686, ItemVersion686, false)
-	blockMapping := mapping.NewBlockMapping(blockStateData686)
+	itemMapping := mappings.item("686", itemRuntimeIDData686, requiredItemList686, ItemVersion686)
+	blockMapping := mappings.block("686", blockStateData686, proto.ID686)
+	latestBlockMapping := latestBlocks()
+	biomeMapping := mappings.biome("748", biomeData748)
+	blockTranslator := NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion686), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion686), false).WithBiomeMapping(biomeMapping, latestBiomes()).WithBlockSubstitutions(parseBlockSubstitutions(blockSubstitutionData729))
 
 	return &Protocol{
 		ver:             "1.21.2",
 		id:              proto.ID686,
-		blockTranslator: NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion686), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion686), false),
-		itemTranslator:  NewItemTranslator(itemMapping, itemMappingLatest, blockMapping, blockMappingLatest),
+		blockTranslator: blockTranslator,
+		itemTranslator:  NewItemTranslator(itemMapping, latestItems(), blockMapping, latestBlockMapping).WithItemSubstitutions(parseItemSubstitutions(itemSubstitutionData686)).WithBlockTranslator(blockTranslator),
+		soundTranslator: NewSoundTranslator(proto.ID686),
 	}
 }
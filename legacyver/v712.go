@@ -4,7 +4,6 @@ import (
 	_ "embed"
 	"github.com/akmalfairuz/legacy-version/internal/chunk"
 	"github.com/akmalfairuz/legacy-version/legacyver/proto"
-	"github.com/akmalfairuz/legacy-version/mapping"
 )
 
 const (
@@ -21,16 +20,22 @@ var (
 	requiredItemList712 []byte
 	//go:embed data/block_states_712.nbt
 	blockStateData712 []byte
+	//go:embed data/item_substitutions_712.json
+	itemSubstitutionData712 []byte
 )
 
 func New712() *Protocol {
-	itemMapping := mapping.NewItemMapping(itemRuntimeIDData712, requiredItemList712, ItemVersion712, false)
-	blockMapping := mapping.NewBlockMapping(blockStateData712)
+	itemMapping := mappings.item("712", itemRuntimeIDData712, requiredItemList712, ItemVersion712)
+	blockMapping := mappings.block("712", blockStateData712, proto.ID712)
+	latestBlockMapping := latestBlocks()
+	biomeMapping := mappings.biome("748", biomeData748)
+	blockTranslator := NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion712), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion712), false).WithBiomeMapping(biomeMapping, latestBiomes()).WithBlockSubstitutions(parseBlockSubstitutions(blockSubstitutionData729))
 
 	return &Protocol{
 		ver:             "1.21.20",
 		id:              proto.ID712,
-		blockTranslator: NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion712), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion712), false),
-		itemTranslator:  NewItemTranslator(itemMapping, itemMappingLatest, blockMapping, blockMappingLatest),
+		blockTranslator: blockTranslator,
+		itemTranslator:  NewItemTranslator(itemMapping, latestItems(), blockMapping, latestBlockMapping).WithItemSubstitutions(parseItemSubstitutions(itemSubstitutionData712)).WithBlockTranslator(blockTranslator),
+		soundTranslator: NewSoundTranslator(proto.ID712),
 	}
 }
@@ -4,7 +4,6 @@ import (
 	_ "embed"
 	"github.com/akmalfairuz/legacy-version/internal/chunk"
 	"github.com/akmalfairuz/legacy-version/legacyver/proto"
-	"github.com/akmalfairuz/legacy-version/mapping"
 )
 
 const (
@@ -21,16 +20,26 @@ var (
 	requiredItemList729 []byte
 	//go:embed data/block_states_729.nbt
 	blockStateData729 []byte
+	// blockSubstitutionData729 holds the block substitutions of 1.21.30. The same blocks need substituting for
+	// every version since 1.20.80, so the older versions use them too.
+	//go:embed data/block_substitutions_729.json
+	blockSubstitutionData729 []byte
+	//go:embed data/item_substitutions_729.json
+	itemSubstitutionData729 []byte
 )
 
 func New729() *Protocol {
-	itemMapping := mapping.NewItemMapping(itemRuntimeIDData729, requiredItemList729, ItemVersion729, false)
-	blockMapping := mapping.NewBlockMapping(blockStateData729)
+	itemMapping := mappings.item("729", itemRuntimeIDData729, requiredItemList729, ItemVersion729)
+	blockMapping := mappings.block("729", blockStateData729, proto.ID729)
+	latestBlockMapping := latestBlocks()
+	biomeMapping := mappings.biome("748", biomeData748)
+	blockTranslator := NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion729), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion729), false).WithBiomeMapping(biomeMapping, latestBiomes()).WithBlockSubstitutions(parseBlockSubstitutions(blockSubstitutionData729))
 
 	return &Protocol{
 		ver:             "1.21.30",
 		id:              proto.ID729,
-		blockTranslator: NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion729), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion729), false),
-		itemTranslator:  NewItemTranslator(itemMapping, itemMappingLatest, blockMapping, blockMappingLatest),
+		blockTranslator: blockTranslator,
+		itemTranslator:  NewItemTranslator(itemMapping, latestItems(), blockMapping, latestBlockMapping).WithItemSubstitutions(parseItemSubstitutions(itemSubstitutionData729)).WithBlockTranslator(blockTranslator),
+		soundTranslator: NewSoundTranslator(proto.ID729),
 	}
 }
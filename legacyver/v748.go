@@ -4,7 +4,6 @@ import (
 	_ "embed"
 	"github.com/akmalfairuz/legacy-version/internal/chunk"
 	"github.com/akmalfairuz/legacy-version/legacyver/proto"
-	"github.com/akmalfairuz/legacy-version/mapping"
 )
 
 const (
@@ -21,16 +20,28 @@ var (
 	requiredItemList748 []byte
 	//go:embed data/block_states_748.nbt
 	blockStateData748 []byte
+	// biomeData748 holds the biome IDs of 1.21.40. They are unchanged since 1.20.80, so the older versions use them
+	// too.
+	//go:embed data/biome_id_map_748.json
+	biomeData748 []byte
+	//go:embed data/block_substitutions_748.json
+	blockSubstitutionData748 []byte
+	//go:embed data/item_substitutions_748.json
+	itemSubstitutionData748 []byte
 )
 
 func New748() *Protocol {
-	itemMapping := mapping.NewItemMapping(itemRuntimeIDData748, requiredItemList748, ItemVersion748, false)
-	blockMapping := mapping.NewBlockMapping(blockStateData748)
+	itemMapping := mappings.item("748", itemRuntimeIDData748, requiredItemList748, ItemVersion748)
+	blockMapping := mappings.block("748", blockStateData748, proto.ID748)
+	latestBlockMapping := latestBlocks()
+	biomeMapping := mappings.biome("748", biomeData748)
+	blockTranslator := NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion748), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion748), false).WithBiomeMapping(biomeMapping, latestBiomes()).WithBlockSubstitutions(parseBlockSubstitutions(blockSubstitutionData748))
 
 	return &Protocol{
 		ver:             "1.21.40",
 		id:              proto.ID748,
-		blockTranslator: NewBlockTranslator(blockMapping, latestBlockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersion748), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersion748), false),
-		itemTranslator:  NewItemTranslator(itemMapping, itemMappingLatest, blockMapping, blockMappingLatest),
+		blockTranslator: blockTranslator,
+		itemTranslator:  NewItemTranslator(itemMapping, latestItems(), blockMapping, latestBlockMapping).WithItemSubstitutions(parseItemSubstitutions(itemSubstitutionData748)).WithBlockTranslator(blockTranslator),
+		soundTranslator: NewSoundTranslator(proto.ID748),
 	}
 }
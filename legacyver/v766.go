@@ -2,7 +2,8 @@ package legacyver
 
 import (
 	_ "embed"
-	"github.com/akmalfairuz/legacy-version/mapping"
+	"github.com/akmalfairuz/legacy-version/internal/chunk"
+	"github.com/akmalfairuz/legacy-version/legacyver/proto"
 )
 
 const (
@@ -19,7 +20,20 @@ var (
 	requiredItemList766 []byte
 	//go:embed data/block_states_766.nbt
 	blockStateData766 []byte
-
-	itemMappingLatest  = mapping.NewItemMapping(itemRuntimeIDData766, requiredItemList766, ItemVersion766, false)
-	blockMappingLatest = mapping.NewBlockMapping(blockStateData766)
+	//go:embed data/biome_id_map_766.json
+	biomeData766 []byte
 )
+
+// New766 returns the Protocol of the latest version. No block or item translation is done for it.
+func New766() *Protocol {
+	itemMapping := latestItems()
+	blockMapping := latestBlocks()
+
+	return &Protocol{
+		ver:             "1.21.50",
+		id:              proto.ID766,
+		blockTranslator: NewBlockTranslator(blockMapping, blockMapping, chunk.NewNetworkPersistentEncoding(blockMapping, BlockVersionLatest), chunk.NewBlockPaletteEncoding(blockMapping, BlockVersionLatest), false),
+		itemTranslator:  NewItemTranslator(itemMapping, itemMapping, blockMapping, blockMapping),
+		soundTranslator: NewSoundTranslator(proto.ID766),
+	}
+}
@@ -0,0 +1,47 @@
+package mapping
+
+import (
+	"encoding/json"
+)
+
+type Biome interface {
+	// BiomeIDToName converts a biome ID to its name.
+	BiomeIDToName(id uint32) (name string, found bool)
+	// BiomeNameToID converts a biome name to its ID.
+	BiomeNameToID(name string) (id uint32, found bool)
+}
+
+type DefaultBiomeMapping struct {
+	// biomeIDsToNames holds a map to translate biome IDs to their names.
+	biomeIDsToNames map[uint32]string
+	// biomeNamesToIDs holds a map to translate biome names to their IDs.
+	biomeNamesToIDs map[string]uint32
+}
+
+func NewBiomeMapping(raw []byte) *DefaultBiomeMapping {
+	var m map[string]uint32
+	if err := json.Unmarshal(raw, &m); err != nil {
+		panic(err)
+	}
+
+	biomeIDsToNames := make(map[uint32]string, len(m))
+	for name, id := range m {
+		biomeIDsToNames[id] = name
+	}
+	return &DefaultBiomeMapping{biomeIDsToNames: biomeIDsToNames, biomeNamesToIDs: m}
+}
+
+func (m *DefaultBiomeMapping) BiomeIDToName(id uint32) (name string, found bool) {
+	name, ok := m.biomeIDsToNames[id]
+	return name, ok
+}
+
+func (m *DefaultBiomeMapping) BiomeNameToID(name string) (id uint32, found bool) {
+	id, ok := m.biomeNamesToIDs[name]
+	return id, ok
+}
+
+// Len returns the amount of biomes held by the mapping.
+func (m *DefaultBiomeMapping) Len() int {
+	return len(m.biomeIDsToNames)
+}
@@ -3,7 +3,9 @@ package mapping
 import (
 	"bytes"
 	"github.com/akmalfairuz/legacy-version/internal"
+	"slices"
 	"sort"
+	"sync/atomic"
 
 	"github.com/df-mc/worldupgrader/blockupgrader"
 	"github.com/sandertv/gophertunnel/minecraft/nbt"
@@ -36,6 +38,8 @@ type DefaultBlockMapping struct {
 
 	// airRID is the runtime ID of the air block in the latest version of the game.
 	airRID uint32
+	// adjustments is the amount of times the runtime IDs of the mapping were changed by Adjust.
+	adjustments atomic.Uint64
 }
 
 func NewBlockMapping(raw []byte) *DefaultBlockMapping {
@@ -79,6 +83,20 @@ func (m *DefaultBlockMapping) WithBlockActorRemapper(downgrader, upgrader func(m
 	return m
 }
 
+// Clone returns a copy of the mapping that may be adjusted without affecting the mapping it was copied from. The
+// copy shares the lookup tables of the original until it is adjusted, as Adjust replaces them instead of modifying
+// them.
+func (m *DefaultBlockMapping) Clone() *DefaultBlockMapping {
+	return &DefaultBlockMapping{
+		states:           slices.Clip(m.states),
+		stateRuntimeIDs:  m.stateRuntimeIDs,
+		runtimeIDToState: m.runtimeIDToState,
+		upgrader:         m.upgrader,
+		downgrader:       m.downgrader,
+		airRID:           m.airRID,
+	}
+}
+
 func (m *DefaultBlockMapping) StateToRuntimeID(state blockupgrader.BlockState) (uint32, bool) {
 	rid, ok := m.stateRuntimeIDs[internal.HashState(blockupgrader.Upgrade(state))]
 	return rid, ok
@@ -117,7 +135,8 @@ func (m *DefaultBlockMapping) Adjust(entries []protocol.BlockEntry) {
 		return
 	}
 
-	adjustedStates := append(m.states, customStates...)
+	// The states are copied, as sorting them in place would reorder the states of the mappings sharing them.
+	adjustedStates := slices.Concat(m.states, customStates)
 	sort.SliceStable(adjustedStates, func(i, j int) bool {
 		stateOne, stateTwo := adjustedStates[i], adjustedStates[j]
 		return stateOne.Name != stateTwo.Name && fnv1.HashString64(stateOne.Name) < fnv1.HashString64(stateTwo.Name)
@@ -129,8 +148,20 @@ func (m *DefaultBlockMapping) Adjust(entries []protocol.BlockEntry) {
 		m.stateRuntimeIDs[internal.HashState(blockupgrader.Upgrade(state))] = uint32(rid)
 		m.runtimeIDToState[uint32(rid)] = state
 	}
+	m.adjustments.Add(1)
+}
+
+// Adjustments returns the amount of times the runtime IDs of the mapping were changed by Adjust. It may be used
+// to find out if data derived from the runtime IDs of the mapping is outdated.
+func (m *DefaultBlockMapping) Adjustments() uint64 {
+	return m.adjustments.Load()
 }
 
 func (m *DefaultBlockMapping) Air() uint32 {
 	return m.airRID
 }
+
+// Len returns the amount of block states held by the mapping.
+func (m *DefaultBlockMapping) Len() int {
+	return len(m.runtimeIDToState)
+}
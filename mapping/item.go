@@ -3,6 +3,8 @@ package mapping
 import (
 	"encoding/json"
 	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"maps"
+	"slices"
 	"sync"
 )
 
@@ -12,6 +14,8 @@ type Item interface {
 	// ItemNameToRuntimeID converts a string ID to an item runtime ID.
 	ItemNameToRuntimeID(string) (int32, bool)
 	RegisterEntry(string) int32
+	// RuntimeIDs returns the runtime IDs of all items held by the mapping, in ascending order.
+	RuntimeIDs() []int32
 	Air() int32
 	ItemVersion() uint16
 }
@@ -70,6 +74,19 @@ func NewItemMapping(itemRuntimeIDData []byte, requiredItemList []byte, itemVersi
 	return &DefaultItemMapping{itemRuntimeIDsToNames: itemRuntimeIDsToNames, itemNamesToRuntimeIDs: itemNamesToRuntimeIDs, itemVersion: itemVersion}
 }
 
+// Clone returns a copy of the mapping. Entries registered with RegisterEntry on the copy are not registered on
+// the mapping it was copied from, and the other way around.
+func (m *DefaultItemMapping) Clone() *DefaultItemMapping {
+	defer m.mu.Unlock()
+	m.mu.Lock()
+	return &DefaultItemMapping{
+		itemRuntimeIDsToNames: maps.Clone(m.itemRuntimeIDsToNames),
+		itemNamesToRuntimeIDs: maps.Clone(m.itemNamesToRuntimeIDs),
+		airRID:                m.airRID,
+		itemVersion:           m.itemVersion,
+	}
+}
+
 func (m *DefaultItemMapping) ItemRuntimeIDToName(runtimeID int32) (name string, found bool) {
 	defer m.mu.Unlock()
 	m.mu.Lock()
@@ -96,6 +113,12 @@ func (m *DefaultItemMapping) RegisterEntry(name string) int32 {
 	return nextRID
 }
 
+func (m *DefaultItemMapping) RuntimeIDs() []int32 {
+	defer m.mu.Unlock()
+	m.mu.Lock()
+	return slices.Sorted(maps.Keys(m.itemRuntimeIDsToNames))
+}
+
 func (m *DefaultItemMapping) Air() int32 {
 	defer m.mu.Unlock()
 	m.mu.Lock()
@@ -107,3 +130,10 @@ func (m *DefaultItemMapping) ItemVersion() uint16 {
 	m.mu.Lock()
 	return m.itemVersion
 }
+
+// Len returns the amount of items held by the mapping.
+func (m *DefaultItemMapping) Len() int {
+	defer m.mu.Unlock()
+	m.mu.Lock()
+	return len(m.itemRuntimeIDsToNames)
+}